@@ -1,41 +1,647 @@
 package jsonbytes
 
-import "errors"
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+)
 
-// IsJson takes a single argument maybeJson []byte and returns nil if maybeJson is a valid JSON value, else an error
-// detailing why it is not a valid JSON value. Note that IsJson does not guarantee that the names of an object are all
-// unique, as rfc7159 and rfc4627 stipulate "The names within an object SHOULD be unique"; there may exist valid reasons
-// in particular circumstances to ignore this.
+// DefaultMaxDepth is the maximum depth of nested objects and arrays permitted when Options.MaxDepth is zero. It is
+// the same limit encoding/json imposes.
+const DefaultMaxDepth = 10000
+
+// MaxSupportedDepth is the greatest depth of nested objects and arrays which can be permitted, and is used when
+// Options.MaxDepth is negative or greater. Values are consumed recursively, so a deeper value could exhaust the
+// goroutine stack, which crashes the whole process rather than returning an error.
+const MaxSupportedDepth = 100000
+
+// Options configures the checks made by the functions in this package beyond those rfc8259 requires. The zero value
+// is what the package level functions use; each of them also has an equivalent method on Options, which behaves the
+// same except for the options set.
+type Options struct {
+	// ValidateUTF8 makes strings and names invalid if they contain bytes which are not valid UTF-8, including overlong
+	// encodings and encoded surrogates, or \u escapes which are not correctly paired UTF-16 surrogates. Without it, any
+	// bytes other than control characters are permitted in strings and names, as rfc8259 does not require a parser to
+	// reject them.
+	ValidateUTF8 bool
+	// MaxDepth is the maximum depth of nested objects and arrays permitted, beyond which a *SyntaxError of kind
+	// SyntaxErrorMaxDepthExceeded is returned. Each nested value is consumed by a recursive call, so this bounds the
+	// amount of goroutine stack used by a hostile value consisting of many '[' bytes. If MaxDepth is zero,
+	// DefaultMaxDepth is used, and if it is negative or greater than MaxSupportedDepth, MaxSupportedDepth is used, as
+	// deeper values could overflow the stack and crash the process.
+	MaxDepth int
+	// DisallowDuplicateNames makes objects invalid if more than one of their members has the same name, once escape
+	// sequences have been decoded, in which case a *DuplicateNameError is returned rather than a *SyntaxError. rfc8259
+	// only says that names SHOULD be unique, and parsers disagree on which member wins when they are not, so a value
+	// with duplicate names can mean different things to different services. Objects with only a few members are
+	// checked without allocating.
+	DisallowDuplicateNames bool
+}
+
+func (options Options) maxDepth() int {
+	if options.MaxDepth == 0 {
+		return DefaultMaxDepth
+	} else if options.MaxDepth < 0 {
+		return MaxSupportedDepth
+	}
+	return min(options.MaxDepth, MaxSupportedDepth)
+}
+
+// IsJson takes a single argument maybeJson []byte and returns nil if maybeJson is a valid JSON value as defined by
+// rfc8259, else an error detailing why it is not a valid JSON value. Note that IsJson does not guarantee that the names
+// of an object are all unique, as rfc7159 and rfc4627 stipulate "The names within an object SHOULD be unique"; there
+// may exist valid reasons in particular circumstances to ignore this, but Options.DisallowDuplicateNames can be set to
+// guarantee it. The error returned is always a *SyntaxError, unless it is a *DuplicateNameError.
 func IsJson(maybeJson []byte) error {
-	jsonValidator, err := newJsonValidator(maybeJson)
+	return Options{}.IsJson(maybeJson)
+}
+
+// IsJson is the same as the package level IsJson, but also makes the checks enabled by options.
+func (options Options) IsJson(maybeJson []byte) error {
+	jsonValidator, err := newJsonValidator(maybeJson, options)
+	if err != nil {
+		return err
+	}
+	err = jsonValidator.consumeValue()
+	if err != nil {
+		return err
+	}
+	if jsonValidator.readIndex != jsonValidator.jsonLength {
+		return jsonValidator.errorTrailingData()
+	}
+	return nil
+}
+
+// ValidateReader reads from reader until it returns io.EOF and returns nil if the bytes read were a valid JSON value,
+// else an error detailing why they were not. Unlike IsJson, the value does not need to be held in memory all at once;
+// it is read in small fixed size chunks, so memory use does not grow with the size of the value. If reader returns an
+// error other than io.EOF, ValidateReader returns that error. Otherwise, the error returned is always a *SyntaxError,
+// unless it is a *DuplicateNameError.
+func ValidateReader(reader io.Reader) error {
+	return Options{}.ValidateReader(reader)
+}
+
+// ValidateReader is the same as the package level ValidateReader, but also makes the checks enabled by options.
+func (options Options) ValidateReader(reader io.Reader) error {
+	jsonValidator, err := newJsonReaderValidator(reader, options)
 	if err != nil {
 		return err
 	}
 	err = jsonValidator.consumeValue()
+	if jsonValidator.readError != nil {
+		return jsonValidator.readError
+	}
 	if err != nil {
 		return err
 	}
 	if jsonValidator.readIndex != jsonValidator.jsonLength {
-		return errors.New("failed to consume entire json string")
+		return jsonValidator.errorTrailingData()
 	}
 	return nil
 }
 
 // RedactAllValues takes a single argument inputJson []byte and returns a new []byte which will be identical to
 // inputJson with all string values replaced with "", numbers replaced with 0, booleans replaced with true and
-// unecessary whitespace characters removed. If inputJson is not a valid JSON value, RedactAllValues will return an
-// error explaining why.
+// unecessary whitespace characters removed. If inputJson is not a valid JSON value, RedactAllValues will return a
+// *SyntaxError explaining why. inputJson is left unmodified.
 func RedactAllValues(inputJson []byte) ([]byte, error) {
-	jsonRedactor, err := newJsonRedactor(inputJson)
+	return Options{}.RedactAllValues(inputJson)
+}
+
+// RedactAllValues is the same as the package level RedactAllValues, but also makes the checks enabled by options.
+func (options Options) RedactAllValues(inputJson []byte) ([]byte, error) {
+	// The redacted JSON is never longer than inputJson, so this is the only allocation needed.
+	return options.redact(inputJson, make([]byte, 0, len(inputJson)), nil)
+}
+
+// RedactAllValuesTo is the same as RedactAllValues, but appends the redacted JSON to dst and returns the extended
+// buffer, in the same manner as strconv.AppendInt, so that a buffer can be reused between calls. inputJson is left
+// unmodified. If inputJson is not a valid JSON value, dst is returned unextended along with a *SyntaxError.
+func RedactAllValuesTo(dst []byte, inputJson []byte) ([]byte, error) {
+	return Options{}.RedactAllValuesTo(dst, inputJson)
+}
+
+// RedactAllValuesTo is the same as the package level RedactAllValuesTo, but also makes the checks enabled by options.
+func (options Options) RedactAllValuesTo(dst []byte, inputJson []byte) ([]byte, error) {
+	redactedJson, err := options.redact(inputJson, dst, nil)
+	if err != nil {
+		return dst, err
+	}
+	return redactedJson, nil
+}
+
+// RedactAllValuesInPlace is the same as RedactAllValues, but is destructive: the redacted JSON is written over the
+// start of inputJson, and the []byte returned is a subslice of inputJson. This avoids allocating a new []byte, but
+// means the original contents of inputJson are lost, even if it is not a valid JSON value, in which case the contents
+// of inputJson are undefined.
+func RedactAllValuesInPlace(inputJson []byte) ([]byte, error) {
+	return Options{}.RedactAllValuesInPlace(inputJson)
+}
+
+// RedactAllValuesInPlace is the same as the package level RedactAllValuesInPlace, but also makes the checks enabled by
+// options.
+func (options Options) RedactAllValuesInPlace(inputJson []byte) ([]byte, error) {
+	return options.redact(inputJson, inputJson[:0], nil)
+}
+
+// Redact takes inputJson []byte and returns a new []byte which will be identical to inputJson with the values selected
+// by rules redacted in the same manner as RedactAllValues, and unecessary whitespace characters removed. The names of
+// objects are never redacted, nor are the values rules do not select. If inputJson is not a valid JSON value, Redact
+// will return a *SyntaxError explaining why. inputJson is left unmodified.
+func Redact(inputJson []byte, rules RedactRules) ([]byte, error) {
+	return Options{}.Redact(inputJson, rules)
+}
+
+// Redact is the same as the package level Redact, but also makes the checks enabled by options.
+func (options Options) Redact(inputJson []byte, rules RedactRules) ([]byte, error) {
+	return options.redact(inputJson, make([]byte, 0, len(inputJson)), &rules)
+}
+
+// Compact appends src to dst with all unecessary whitespace characters removed and every value kept verbatim, and
+// returns the extended buffer, like encoding/json.Compact. If src is not a valid JSON value, dst is returned
+// unextended along with a *SyntaxError explaining why.
+func Compact(dst []byte, src []byte) ([]byte, error) {
+	return Options{}.Compact(dst, src)
+}
+
+// Compact is the same as the package level Compact, but also makes the checks enabled by options.
+func (options Options) Compact(dst []byte, src []byte) ([]byte, error) {
+	compactedJson, err := options.compact(src, dst)
+	if err != nil {
+		return dst, err
+	}
+	return compactedJson, nil
+}
+
+// CompactInPlace is the same as Compact, but is destructive in the same manner as RedactAllValuesInPlace: the
+// compacted JSON is written over the start of json, and the []byte returned is a subslice of json.
+func CompactInPlace(json []byte) ([]byte, error) {
+	return Options{}.CompactInPlace(json)
+}
+
+// CompactInPlace is the same as the package level CompactInPlace, but also makes the checks enabled by options.
+func (options Options) CompactInPlace(json []byte) ([]byte, error) {
+	return options.compact(json, json[:0])
+}
+
+func (options Options) compact(inputJson []byte, output []byte) ([]byte, error) {
+	jsonRedactor, err := newJsonRedactor(inputJson, output, options)
+	if err != nil {
+		return nil, err
+	}
+	jsonRedactor.keepValues = true
+	return jsonRedactor.redact()
+}
+
+// Indent appends src to dst laid out for people to read, and returns the extended buffer, like encoding/json.Indent.
+// Each member of an object and element of an array begins on a new line, starting with prefix followed by a copy of
+// indent for each level of nesting. The output does not begin with prefix, nor end with a newline, so that it can be
+// embedded in other output. If src is not a valid JSON value, dst is returned unextended along with a *SyntaxError
+// explaining why.
+func Indent(dst []byte, src []byte, prefix string, indent string) ([]byte, error) {
+	return IndentOptions{Prefix: prefix, Indentation: indent}.Indent(dst, src)
+}
+
+// IndentOptions configures how Indent lays out JSON.
+type IndentOptions struct {
+	// Prefix and Indentation are the same as the prefix and indent arguments of the package level Indent.
+	Prefix      string
+	Indentation string
+	// SortKeys sorts the members of each object by name, after their escape sequences have been decoded, in the same
+	// order as encoding/json sorts the keys of maps. Members with the same name are kept in their original order.
+	SortKeys bool
+	// CompactArrays lays out arrays which contain no objects or arrays on a single line, like [1, 2, 3], provided it
+	// would be no wider than MaxWidth.
+	CompactArrays bool
+	// MaxWidth is the maximum width of a line, in bytes and including Prefix and Indentation, on which CompactArrays
+	// will lay out an array. If it is zero, there is no maximum.
+	MaxWidth int
+	// Options configures the checks made on the JSON.
+	Options Options
+}
+
+// Indent is the same as the package level Indent, but lays out src as configured by indentOptions.
+func (indentOptions IndentOptions) Indent(dst []byte, src []byte) ([]byte, error) {
+	jsonIndenter, err := newJsonIndenter(src, dst, &indentOptions)
+	if err != nil {
+		return dst, err
+	}
+	indentedJson, err := jsonIndenter.indent()
+	if err != nil {
+		return dst, err
+	}
+	return indentedJson, nil
+}
+
+// Get returns the value at path in json, along with its Kind, without unmarshalling json. Each element of path is
+// either the name of a member of an object, or the decimal index of an element of an array, so Get(json, "items", "0",
+// "type") returns the type member of the first element of the items member of json. The value is returned as a
+// subslice of json, exactly as it appears there, so a string includes its quotes and escape sequences. If a name
+// appears more than once in an object, the first member with that name is used.
+//
+// Get only reads as much of json as it needs to: members and elements not on path are checked to be valid JSON, but
+// nothing following the value is read at all, so Get does not guarantee that json is valid. If there is no value at
+// path, ErrPathNotFound is returned, and if json is found not to be valid JSON, a *SyntaxError is returned. Get does
+// not allocate.
+func Get(json []byte, path ...string) ([]byte, Kind, error) {
+	return Options{}.Get(json, path...)
+}
+
+// Get is the same as the package level Get, but also makes the checks enabled by options.
+func (options Options) Get(json []byte, path ...string) ([]byte, Kind, error) {
+	jsonValidator, err := newJsonValidator(json, options)
+	if err != nil {
+		return nil, KindInvalid, err
+	}
+	valueStart, err := jsonValidator.consumeToPath(path)
+	if err != nil {
+		return nil, KindInvalid, err
+	}
+	err = jsonValidator.consumeValue()
+	if err != nil {
+		return nil, KindInvalid, err
+	}
+	return json[valueStart:jsonValidator.valueEnd()], kindOf(json[valueStart]), nil
+}
+
+// GetMany is the same as calling Get for each path in paths, but reads json only once, from left to right, and stops
+// reading as soon as the values at all of the paths have been found. The value at each path is returned at the same
+// index as the path, or nil if there is no value at the path. If json is found not to be valid JSON before all of the
+// values have been found, a *SyntaxError is returned.
+func GetMany(json []byte, paths [][]string) ([][]byte, error) {
+	return Options{}.GetMany(json, paths)
+}
+
+// GetMany is the same as the package level GetMany, but also makes the checks enabled by options.
+func (options Options) GetMany(json []byte, paths [][]string) ([][]byte, error) {
+	jsonValidator, err := newJsonValidator(json, options)
 	if err != nil {
 		return nil, err
 	}
-	err = jsonRedactor.consumeValue()
+	getter := &pathsGetter{
+		paths:     paths,
+		values:    make([][]byte, len(paths)),
+		done:      make([]bool, len(paths)),
+		remaining: len(paths),
+		active:    make([]int, len(paths)),
+	}
+	if len(paths) == 0 {
+		return getter.values, nil
+	}
+	for pathIndex := range paths {
+		getter.active[pathIndex] = pathIndex
+	}
+	err = jsonValidator.consumeValueOnPaths(getter, 0, 0)
+	if err != nil && err != errAllPathsFound {
+		return nil, err
+	}
+	return getter.values, nil
+}
+
+// Set returns a new []byte which is json with the value at path, as described by Get, replaced with value. If there is
+// no value at path, but the last element of path is the name of a member that could be added to an existing object, the
+// member is added after the last member of the object. Otherwise, ErrPathNotFound is returned. Leading and trailing
+// whitespace is removed from value, but json is otherwise left exactly as it was, including its whitespace. If json
+// is not a valid JSON value, a *SyntaxError is returned, and if value is not, one wrapped in an error which says so is
+// returned. json and value are left unmodified.
+func Set(json []byte, path []string, value []byte) ([]byte, error) {
+	return Options{}.Set(json, path, value)
+}
+
+// Set is the same as the package level Set, but also makes the checks enabled by options.
+func (options Options) Set(json []byte, path []string, value []byte) ([]byte, error) {
+	err := options.IsJson(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value: %w", err)
+	}
+	value = bytes.TrimSpace(value)
+	location, err := options.locateForEdit(json, path)
+	if err != nil {
+		return nil, err
+	}
+	if location.found {
+		return splice(json, location.valueStart, location.valueEnd, value), nil
+	}
+	name := path[len(path)-1]
+	insertIndex := location.previousEnd
+	member := make([]byte, 0, len(name)+len(value)+4)
+	if insertIndex == -1 {
+		insertIndex = location.closeIndex
+	} else {
+		member = append(member, ',')
+	}
+	member = append(member, '"')
+	member = appendEscaped(member, []byte(name))
+	member = append(member, '"', ':')
+	member = append(member, value...)
+	return splice(json, insertIndex, insertIndex, member), nil
+}
+
+// Delete returns a new []byte which is json with the value at path, as described by Get, removed, along with its name
+// if it is the value of a member of an object, and a comma separating it from the members or elements around it. If
+// there is no value at path, or path is empty, ErrPathNotFound is returned. If json is not a valid JSON value, a
+// *SyntaxError is returned. json is left unmodified.
+func Delete(json []byte, path []string) ([]byte, error) {
+	return Options{}.Delete(json, path)
+}
+
+// Delete is the same as the package level Delete, but also makes the checks enabled by options.
+func (options Options) Delete(json []byte, path []string) ([]byte, error) {
+	location, err := options.locateForEdit(json, path)
+	if err != nil {
+		return nil, err
+	}
+	if !location.found || len(path) == 0 {
+		return nil, ErrPathNotFound
+	}
+	if location.previousEnd != -1 {
+		return splice(json, location.previousEnd, location.valueEnd, nil), nil
+	} else if location.nextStart != -1 {
+		return splice(json, location.memberStart, location.nextStart, nil), nil
+	}
+	return splice(json, location.memberStart, location.valueEnd, nil), nil
+}
+
+// ArrayAppend returns a new []byte which is json with value appended to the array at path, as described by Get.
+// Leading and trailing whitespace is removed from value. If there is no value at path, ErrPathNotFound is returned,
+// and if it is not an array, an error which says so is returned. If json is not a valid JSON value, a *SyntaxError is
+// returned, and if value is not, one wrapped in an error which says so is returned. json and value are left
+// unmodified.
+func ArrayAppend(json []byte, path []string, value []byte) ([]byte, error) {
+	return Options{}.ArrayAppend(json, path, value)
+}
+
+// ArrayAppend is the same as the package level ArrayAppend, but also makes the checks enabled by options.
+func (options Options) ArrayAppend(json []byte, path []string, value []byte) ([]byte, error) {
+	err := options.IsJson(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value: %w", err)
+	}
+	value = bytes.TrimSpace(value)
+	location, err := options.locateForEdit(json, path)
+	if err != nil {
+		return nil, err
+	}
+	if !location.found {
+		return nil, ErrPathNotFound
+	}
+	if kind := kindOf(json[location.valueStart]); kind != KindArray {
+		return nil, fmt.Errorf("expected an array at path but found %s", kind)
+	}
+	// The value is inserted after the last element, or the opening byte if there is none, so that any whitespace
+	// before the closing byte stays where it is.
+	insertIndex := len(bytes.TrimRight(json[:location.valueEnd-1], " \t\n\r"))
+	if json[insertIndex-1] == '[' {
+		return splice(json, insertIndex, insertIndex, value), nil
+	}
+	return splice(json, insertIndex, insertIndex, append([]byte{','}, value...)), nil
+}
+
+func (options Options) locateForEdit(json []byte, path []string) (editLocation, error) {
+	err := options.IsJson(json)
+	if err != nil {
+		return editLocation{}, err
+	}
+	jsonValidator, err := newJsonValidator(json, options)
+	if err != nil {
+		return editLocation{}, err
+	}
+	return jsonValidator.locateForEdit(path)
+}
+
+// splice returns a new []byte which is json with the bytes from start to end replaced with replacement.
+func splice(json []byte, start int, end int, replacement []byte) []byte {
+	spliced := make([]byte, 0, len(json)-(end-start)+len(replacement))
+	spliced = append(spliced, json[:start]...)
+	spliced = append(spliced, replacement...)
+	return append(spliced, json[end:]...)
+}
+
+// Walk reads json from start to end, calling the methods of visitor for each part of it as it is read, and returns nil
+// if it was a valid JSON value. If it is not, a *SyntaxError is returned, having already called visitor for every part
+// of json before the error. If visitor returns an error, Walk returns it, unless it is SkipSubtree or StopWalk, which
+// are described with them.
+func Walk(json []byte, visitor Visitor) error {
+	return Options{}.Walk(json, visitor)
+}
+
+// Walk is the same as the package level Walk, but also makes the checks enabled by options.
+func (options Options) Walk(json []byte, visitor Visitor) error {
+	jsonValidator, err := newJsonValidator(json, options)
+	if err != nil {
+		return err
+	}
+	walker := jsonWalker{jsonValidator: *jsonValidator, visitor: visitor}
+	return walker.walk()
+}
+
+// IsJsonLines returns nil if jsonLines is valid JSON Lines, where each line which is not blank is a record that must
+// be a valid JSON value on its own, else a *LineError for the first record which is not. An empty jsonLines, with no
+// records at all, is valid.
+func IsJsonLines(jsonLines []byte) error {
+	return JsonLinesOptions{}.IsJsonLines(jsonLines)
+}
+
+// ValidateJsonLinesReader is the same as IsJsonLines, but reads the JSON Lines from reader until it returns io.EOF,
+// holding only one line in memory at a time. If reader returns an error other than io.EOF, ValidateJsonLinesReader
+// returns that error.
+func ValidateJsonLinesReader(reader io.Reader) error {
+	return JsonLinesOptions{}.ValidateJsonLinesReader(reader)
+}
+
+// RedactJsonLines returns a new []byte which is jsonLines with each record redacted in the same manner as
+// RedactAllValues. Line endings and blank lines are kept, so each record stays on the same line. If a record is not a
+// valid JSON value, nil is returned along with a *LineError for it.
+func RedactJsonLines(jsonLines []byte) ([]byte, error) {
+	return JsonLinesOptions{}.RedactJsonLines(jsonLines)
+}
+
+// RedactJsonLinesReader is the same as RedactJsonLines, but reads the JSON Lines from reader until it returns io.EOF,
+// and writes each line to writer once it has been redacted, holding only one line in memory at a time. If a record is
+// not a valid JSON value, the lines before it will already have been written. If reader or writer returns an error,
+// RedactJsonLinesReader returns that error.
+func RedactJsonLinesReader(writer io.Writer, reader io.Reader) error {
+	return JsonLinesOptions{}.RedactJsonLinesReader(writer, reader)
+}
+
+// JsonLinesOptions configures how IsJsonLines, ValidateJsonLinesReader, RedactJsonLines and RedactJsonLinesReader
+// treat JSON Lines.
+type JsonLinesOptions struct {
+	// ContinueOnError carries on past records which are not valid JSON values, instead of stopping at the first of
+	// them. A *LineError is returned for each of them, joined with errors.Join if there is more than one. When
+	// redacting, each of them is replaced with an empty line, and the rest of the output is returned alongside the
+	// errors.
+	ContinueOnError bool
+	// Options configures the checks made on each record.
+	Options Options
+}
+
+// IsJsonLines is the same as the package level IsJsonLines, but treats jsonLines as configured by jsonLinesOptions.
+func (jsonLinesOptions JsonLinesOptions) IsJsonLines(jsonLines []byte) error {
+	processor := jsonLinesProcessor{options: jsonLinesOptions}
+	return processor.process(&jsonLinesReader{jsonLines: jsonLines})
+}
+
+// ValidateJsonLinesReader is the same as the package level ValidateJsonLinesReader, but treats the JSON Lines as
+// configured by jsonLinesOptions.
+func (jsonLinesOptions JsonLinesOptions) ValidateJsonLinesReader(reader io.Reader) error {
+	processor := jsonLinesProcessor{options: jsonLinesOptions}
+	return processor.process(&jsonLinesReader{reader: bufio.NewReader(reader)})
+}
+
+// RedactJsonLines is the same as the package level RedactJsonLines, but treats jsonLines as configured by
+// jsonLinesOptions.
+func (jsonLinesOptions JsonLinesOptions) RedactJsonLines(jsonLines []byte) ([]byte, error) {
+	processor := jsonLinesProcessor{options: jsonLinesOptions, redact: true, output: make([]byte, 0, len(jsonLines))}
+	err := processor.process(&jsonLinesReader{jsonLines: jsonLines})
+	if err != nil && !jsonLinesOptions.ContinueOnError {
+		return nil, err
+	}
+	return processor.output, err
+}
+
+// RedactJsonLinesReader is the same as the package level RedactJsonLinesReader, but treats the JSON Lines as
+// configured by jsonLinesOptions.
+func (jsonLinesOptions JsonLinesOptions) RedactJsonLinesReader(writer io.Writer, reader io.Reader) error {
+	processor := jsonLinesProcessor{options: jsonLinesOptions, redact: true, writer: writer}
+	return processor.process(&jsonLinesReader{reader: bufio.NewReader(reader)})
+}
+
+// Split returns an iterator over the JSON values in json, which may be concatenated back to back, like {}{}[1], or be
+// separated by whitespace. Each value is yielded as a subslice of json, without the whitespace around it. If json is
+// not a sequence of valid JSON values, the values before the one which is not are yielded, and then a *SyntaxError is
+// yielded with a nil value, after which iteration ends. An empty json, or one of only whitespace, yields nothing.
+func Split(json []byte) iter.Seq2[[]byte, error] {
+	return Options{}.Split(json)
+}
+
+// Split is the same as the package level Split, but also makes the checks enabled by options on each value.
+func (options Options) Split(json []byte) iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		if len(json) == 0 {
+			return
+		}
+		jsonValidator, err := newJsonValidator(json, options)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		jsonValidator.consumeWhitespace()
+		for jsonValidator.readIndex != jsonValidator.jsonLength {
+			valueStart := jsonValidator.readIndex
+			err = jsonValidator.consumeValue()
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(json[valueStart:jsonValidator.valueEnd()], nil) {
+				return
+			}
+		}
+	}
+}
+
+// Conforms returns nil if json has the same shape as template, such as a response from an API redacted with
+// RedactAllValues, else a *ConformanceError describing the first way in which it does not. Having the same shape means
+// that every value in json is of the same Kind as the value at the same path in template, where objects have exactly
+// the same names, in any order, and every element of an array has the same shape as the first element of the array in
+// template. Any elements are allowed in an array which is empty in template, and the other elements of an array in
+// template are ignored. If a name appears more than once in an object in template, the last member with it is used.
+// If json is not a valid JSON value, a *SyntaxError is returned, and if template is not, one wrapped in an error which
+// says so is returned.
+func Conforms(json []byte, template []byte) error {
+	return Options{}.Conforms(json, template)
+}
+
+// Conforms is the same as the package level Conforms, but also makes the checks enabled by options on both json and
+// template.
+func (options Options) Conforms(json []byte, template []byte) error {
+	err := options.IsJson(template)
+	if err != nil {
+		return fmt.Errorf("invalid template: %w", err)
+	}
+	err = options.IsJson(json)
+	if err != nil {
+		return err
+	}
+	// Both have been validated, so the other checks need not be made again.
+	options = Options{MaxDepth: options.MaxDepth}
+	jsonValidator, err := newJsonValidator(json, options)
+	if err != nil {
+		return err
+	}
+	jsonValidator.consumeWhitespace()
+	conformer := conformer{options: options, document: jsonValidator}
+	return conformer.conformValue(bytes.TrimSpace(template))
+}
+
+// InferSchema returns a JSON Schema (draft 2020-12) describing the union of the shapes of samples, which may be used
+// to bootstrap a contract for an API which is not documented. Each value is described by the types of the values
+// observed at the same place in samples, where a number is an integer if it is written without a fraction or exponent,
+// and strings by the format they were all in, of date-time, date, time or uuid, if any. Objects are described by the
+// properties observed in any of them, with the members observed in every one required, and the fraction of the
+// objects each member was observed in as the annotation x-frequency. Arrays are described by the union of the shapes
+// of their elements. The schema is compact, and its properties are in the order they were first observed. If a
+// sample is not a valid JSON value, a *SyntaxError wrapped in an error which says which sample it is is returned, and
+// if there are no samples, an error which says so is returned.
+func InferSchema(samples ...[]byte) ([]byte, error) {
+	return Options{}.InferSchema(samples...)
+}
+
+// InferSchema is the same as the package level InferSchema, but also makes the checks enabled by options on each
+// sample.
+func (options Options) InferSchema(samples ...[]byte) ([]byte, error) {
+	if len(samples) == 0 {
+		return nil, errors.New("no samples to infer a schema from")
+	}
+	inferrer := schemaInferrer{}
+	for index, sample := range samples {
+		err := options.IsJson(sample)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sample %d: %w", index, err)
+		}
+		// The sample has been validated, so the other checks need not be made again.
+		jsonValidator, _ := newJsonValidator(sample, Options{MaxDepth: options.MaxDepth})
+		jsonValidator.consumeWhitespace()
+		inferrer.observeValue(jsonValidator, &inferrer.root)
+	}
+	schema := []byte(`{"$schema":"https://json-schema.org/draft/2020-12/schema",`)
+	schema = inferrer.root.appendKeywords(schema)
+	return append(schema, '}'), nil
+}
+
+// Stats returns statistics describing the complexity of json, such as the number of values of each kind and how
+// deeply they are nested, which may be useful for monitoring the payloads a service handles. They are counted in a
+// single pass over json, without allocating. If json is not a valid JSON value, a *SyntaxError is returned.
+func Stats(json []byte) (DocumentStats, error) {
+	return Options{}.Stats(json)
+}
+
+// Stats is the same as the package level Stats, but also makes the checks enabled by options.
+func (options Options) Stats(json []byte) (DocumentStats, error) {
+	jsonValidator, err := newJsonValidator(json, options)
+	if err != nil {
+		return DocumentStats{}, err
+	}
+	collector := jsonStatsCollector{jsonValidator: *jsonValidator}
+	err = collector.collect()
+	if err != nil {
+		return DocumentStats{}, err
+	}
+	return collector.stats, nil
+}
+
+// redact appends inputJson to output with the values selected by rules redacted, or every value if rules is nil.
+func (options Options) redact(inputJson []byte, output []byte, rules *RedactRules) ([]byte, error) {
+	jsonRedactor, err := newJsonRedactor(inputJson, output, options)
 	if err != nil {
 		return nil, err
 	}
-	if jsonRedactor.jsonValidator.readIndex != jsonRedactor.jsonValidator.jsonLength {
-		return nil, errors.New("failed to consume entire json string")
+	if rules != nil {
+		jsonRedactor.matcher = newRedactRulesMatcher(rules)
 	}
-	return jsonRedactor.jsonValidator.json[:jsonRedactor.writeIndex], nil
+	return jsonRedactor.redact()
 }
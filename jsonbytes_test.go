@@ -1,236 +1,2081 @@
 package jsonbytes
 
 import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"math"
 	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/stretchr/testify/require"
 )
 
+var validJsonTestCases []string = []string{
+	// Strings
+	"\"\"",
+	"\"a\"",
+	"\"foo\"",
+	"\"\\\"\"",
+	"\"\\\\\"",
+	"\"\\\\\\\"\"",
+	"\"\x7f\"",
+	// Numbers
+	"-3.14159E+123",
+	"-3.14159e+123",
+	"-3.14159e123",
+	"1e5",
+	"1E5",
+	"-3.14159",
+	"-1",
+	"-0.1",
+	"-3.14159E-123",
+	"-3.14159e-123",
+	"-0",
+	"0",
+	"3.14159E-123",
+	"3.14159e-123",
+	"0.1",
+	"1",
+	"3.14159",
+	"3.14159E+123",
+	"3.14159e+123",
+	// Booleans & null
+	"true",
+	"false",
+	"null",
+	// Various arrays
+	"[]",
+	"[0]",
+	"[0,1,2,3,4,5,6,7,8,9]",
+	// Various objects
+	"{}",
+	"{\"\":\"\"}",
+	"{\"foo\":\"bar\"}",
+	"{\"foo\":0}",
+	"{\"foo\":1}",
+	"{\"foo\":0.1}",
+	"{\"foo\":3.14159}",
+	"{\"foo\":{}}",
+	"{\"foo\":{\"bar\":\"baz\"}}",
+	"{\"foo\":[]}",
+	"{\"foo\":[\"bar\"]}",
+	"{\"foo\": [\"bar\"]}",
+	"{\"foo\":true}",
+	"{\"foo\":false}",
+	"{\"foo\":null}",
+	// Whitespace in various positions
+	" 0 ",
+	"  0  ",
+	"   0   ",
+	" -0 ",
+	" 0.1 ",
+	" 1 ",
+	" 3.14159 ",
+	" 3.14159E+123 ",
+	" true ",
+	" false ",
+	" null ",
+	" [ ] ",
+	" { } ",
+	" { \"foo\" : \"bar\" } ",
+	" { \"foo\" : 0 } ",
+	" { \"foo\" : 1 } ",
+	" { \"foo\" : 0.1 } ",
+	" { \"foo\" : 3.14159 } ",
+	" { \"foo\" : { \"bar\" : \"baz\" } } ",
+	" { \"foo\" : [ \"bar\" ] } ",
+	" { \"foo\" : true } ",
+	" { \"foo\" : false } ",
+	" { \"foo\" : null } ",
+}
+
 func TestIsJson(t *testing.T) {
-	testCases := []string{
+	for _, testCase := range validJsonTestCases {
+		t.Run(
+			testCase,
+			func(t *testing.T) {
+				err := IsJson([]byte(testCase))
+				require.Nil(t, err)
+			},
+		)
+	}
+}
+
+func TestValidateReader(t *testing.T) {
+	for _, testCase := range validJsonTestCases {
+		t.Run(
+			testCase,
+			func(t *testing.T) {
+				err := ValidateReader(strings.NewReader(testCase))
+				require.Nil(t, err)
+				err = ValidateReader(iotest.OneByteReader(strings.NewReader(testCase)))
+				require.Nil(t, err)
+			},
+		)
+	}
+	for _, testCase := range testJsonCases {
+		t.Run(
+			testCase.name,
+			func(t *testing.T) {
+				err := ValidateReader(bytes.NewReader(*testCase.testJson))
+				require.Nil(t, err)
+			},
+		)
+	}
+}
+
+func TestValidateReaderReadError(t *testing.T) {
+	readError := errors.New("connection reset")
+	err := ValidateReader(io.MultiReader(strings.NewReader("[1,2"), iotest.ErrReader(readError)))
+	require.Equal(t, readError, err)
+	err = ValidateReader(io.MultiReader(strings.NewReader("[1,2]"), iotest.ErrReader(readError)))
+	require.Equal(t, readError, err)
+}
+
+func TestValidator(t *testing.T) {
+	for _, testCase := range testJsonCases {
+		t.Run(
+			testCase.name,
+			func(t *testing.T) {
+				validator := NewValidator()
+				for chunk := range slices.Chunk(*testCase.testJson, 1000) {
+					_, err := validator.Write(chunk)
+					require.Nil(t, err)
+				}
+				require.Nil(t, validator.Close())
+			},
+		)
+	}
+	t.Run(
+		"WriteAfterInvalid",
+		func(t *testing.T) {
+			validator := NewValidator()
+			_, err := validator.Write([]byte("[1,2"))
+			require.Nil(t, err)
+			_, err = validator.Write([]byte("}"))
+			require.Nil(t, err)
+			_, err = validator.Write([]byte("]"))
+			require.NotNil(t, err)
+			require.Equal(t, "expected any of ,] at index 4 but read '}'", err.Error())
+			err = validator.Close()
+			require.NotNil(t, err)
+			require.Equal(t, "expected any of ,] at index 4 but read '}'", err.Error())
+		},
+	)
+}
+
+func TestRedactAllValues(t *testing.T) {
+	testCases := []struct {
+		testJson     string
+		expectedJson string
+	}{
 		// Strings
-		"\"\"",
-		"\"a\"",
-		"\"foo\"",
-		"\"\\\"\"",
+		{"\"\"", "\"\""},
+		{"\"a\"", "\"\""},
+		{"\"foo\"", "\"\""},
+		{"\"\\\"\"", "\"\""},
 		// Numbers
-		"-3.14159E+123",
-		"-3.14159e+123",
-		"-3.14159",
-		"-1",
-		"-0.1",
-		"-3.14159E-123",
-		"-3.14159e-123",
-		"-0",
-		"0",
-		"3.14159E-123",
-		"3.14159e-123",
-		"0.1",
-		"1",
-		"3.14159",
-		"3.14159E+123",
-		"3.14159e+123",
+		{"-3.14159E+123", "0"},
+		{"-3.14159e+123", "0"},
+		{"-3.14159", "0"},
+		{"-1", "0"},
+		{"-0.1", "0"},
+		{"-3.14159E-123", "0"},
+		{"-3.14159e-123", "0"},
+		{"-0", "0"},
+		{"0", "0"},
+		{"3.14159E-123", "0"},
+		{"3.14159e-123", "0"},
+		{"0.1", "0"},
+		{"1", "0"},
+		{"3.14159", "0"},
+		{"3.14159E+123", "0"},
+		{"3.14159e+123", "0"},
 		// Booleans & null
-		"true",
-		"false",
-		"null",
+		{"true", "true"},
+		{"false", "true"},
+		{"null", "null"},
 		// Various arrays
-		"[]",
-		"[0]",
-		"[0,1,2,3,4,5,6,7,8,9]",
+		{"[]", "[]"},
+		{"[0]", "[0]"},
+		{"[0,1,2,3,4,5,6,7,8,9]", "[0,0,0,0,0,0,0,0,0,0]"},
 		// Various objects
-		"{}",
-		"{\"\":\"\"}",
-		"{\"foo\":\"bar\"}",
-		"{\"foo\":0}",
-		"{\"foo\":1}",
-		"{\"foo\":0.1}",
-		"{\"foo\":3.14159}",
-		"{\"foo\":{}}",
-		"{\"foo\":{\"bar\":\"baz\"}}",
-		"{\"foo\":[]}",
-		"{\"foo\":[\"bar\"]}",
-		"{\"foo\": [\"bar\"]}",
-		"{\"foo\":true}",
-		"{\"foo\":false}",
-		"{\"foo\":null}",
+		{"{}", "{}"},
+		{"{\"\":\"\"}", "{\"\":\"\"}"},
+		{"{\"foo\":\"bar\"}", "{\"foo\":\"\"}"},
+		{"{\"foo\":0}", "{\"foo\":0}"},
+		{"{\"foo\":1}", "{\"foo\":0}"},
+		{"{\"foo\":0.1}", "{\"foo\":0}"},
+		{"{\"foo\":3.14159}", "{\"foo\":0}"},
+		{"{\"foo\":{}}", "{\"foo\":{}}"},
+		{"{\"foo\":{\"bar\":\"baz\"}}", "{\"foo\":{\"bar\":\"\"}}"},
+		{"{\"foo\":[]}", "{\"foo\":[]}"},
+		{"{\"foo\":[\"bar\"]}", "{\"foo\":[\"\"]}"},
+		{"{\"foo\": [\"bar\"]}", "{\"foo\":[\"\"]}"},
+		{"{\"foo\":true}", "{\"foo\":true}"},
+		{"{\"foo\":false}", "{\"foo\":true}"},
+		{"{\"foo\":null}", "{\"foo\":null}"},
 		// Whitespace in various positions
-		" 0 ",
-		"  0  ",
-		"   0   ",
-		" -0 ",
-		" 0.1 ",
-		" 1 ",
-		" 3.14159 ",
-		" 3.14159E+123 ",
-		" true ",
-		" false ",
-		" null ",
-		" [ ] ",
-		" { } ",
-		" { \"foo\" : \"bar\" } ",
-		" { \"foo\" : 0 } ",
-		" { \"foo\" : 1 } ",
-		" { \"foo\" : 0.1 } ",
-		" { \"foo\" : 3.14159 } ",
-		" { \"foo\" : { \"bar\" : \"baz\" } } ",
-		" { \"foo\" : [ \"bar\" ] } ",
-		" { \"foo\" : true } ",
-		" { \"foo\" : false } ",
-		" { \"foo\" : null } ",
+		{" 0 ", "0"},
+		{"  0  ", "0"},
+		{"   0   ", "0"},
+		{" -0 ", "0"},
+		{" 0.1 ", "0"},
+		{" 1 ", "0"},
+		{" 3.14159 ", "0"},
+		{" 3.14159E+123 ", "0"},
+		{" true ", "true"},
+		{" false ", "true"},
+		{" null ", "null"},
+		{" [ ] ", "[]"},
+		{" { } ", "{}"},
+		{" { \"foo\" : \"bar\" } ", "{\"foo\":\"\"}"},
+		{" { \"foo\" : 0 } ", "{\"foo\":0}"},
+		{" { \"foo\" : 1 } ", "{\"foo\":0}"},
+		{" { \"foo\" : 0.1 } ", "{\"foo\":0}"},
+		{" { \"foo\" : 3.14159 } ", "{\"foo\":0}"},
+		{" { \"foo\" : { \"bar\" : \"baz\" } } ", "{\"foo\":{\"bar\":\"\"}}"},
+		{" { \"foo\" : [ \"bar\" ] } ", "{\"foo\":[\"\"]}"},
+		{" { \"foo\" : true } ", "{\"foo\":true}"},
+		{" { \"foo\" : false } ", "{\"foo\":true}"},
+		{" { \"foo\" : null } ", "{\"foo\":null}"},
+	}
+	for _, testCase := range testCases {
+		t.Run(
+			testCase.testJson,
+			func(t *testing.T) {
+				testJson := []byte(testCase.testJson)
+				redactedJson, err := RedactAllValues(testJson)
+				require.Nil(t, err)
+				require.Equal(t, testCase.expectedJson, string(redactedJson))
+				require.Equal(t, testCase.testJson, string(testJson))
+				redactedJson, err = RedactAllValuesTo([]byte("prefix"), testJson)
+				require.Nil(t, err)
+				require.Equal(t, "prefix"+testCase.expectedJson, string(redactedJson))
+				require.Equal(t, testCase.testJson, string(testJson))
+				redactedJson, err = RedactAllValuesInPlace(testJson)
+				require.Nil(t, err)
+				require.Equal(t, testCase.expectedJson, string(redactedJson))
+				require.Equal(t, &testJson[0], &redactedJson[0])
+			},
+		)
+	}
+}
+
+type invalidJsonTestCase struct {
+	testJson      string
+	expectedError string
+}
+
+var invalidJsonTestCases []invalidJsonTestCase = []invalidJsonTestCase{
+	{"", "jsonvalidator needs more than zero bytes"},
+	{" ", "read head ran out of json"},
+	{"j", "expected any of \"10123456789{[tfn at index 0 but read 'j'"},
+	{"\"", "read head ran out of json"},
+	{"\"\x1f", "expected any codepoint except \" or \\ or control characters at index 1 but read '\x1f'"},
+	{"\"\\z", "expected any of \"/\\bfnrtu at index 2 but read 'z'"},
+	{"\"\\u", "expected 4 hex digits but reached end of json"},
+	{"\"\\u0", "expected 4 hex digits but reached end of json"},
+	{"\"\\u00", "expected 4 hex digits but reached end of json"},
+	{"\"\\u000", "expected 4 hex digits but reached end of json"},
+	{"\"\\u0000", "expected \" but reached end of json"},
+	{"\"\\u000z", "expected 4 hex digits at index 6 but read 'z'"},
+	{"\"\\u00000", "expected \" but reached end of json"},
+	{"\"\\u0000z", "expected \" but reached end of json"},
+	{"{", "read head ran out of json"},
+	{"{\"foo\":\"bar\"", "read head ran out of json"},
+	{"{\"foo\":\"bar\",", "read head ran out of json"},
+	{"{\"foo", "expected \" but reached end of json"},
+	{"{\"foo\" ", "read head ran out of json"},
+	{"{\"foo\";", "expected : at index 6 but read ';'"},
+	{"{\"foo\":", "read head ran out of json"},
+	{"[", "read head ran out of json"},
+	{"[,", "expected any of \"10123456789{[tfn at index 1 but read ','"},
+	{"[\"", "read head ran out of json"},
+	{"\"f", "expected \" but reached end of json"},
+	{"-", "expected any of 0123456789 but reached end of json"},
+	{"0.", "expected any of 0123456789 but reached end of json"},
+	{"0E", "expected any of +-0123456789 but reached end of json"},
+	{"0E-", "expected any of 0123456789 but reached end of json"},
+	{"foo", "expected a at index 1 but read 'o'"},
+	{"f", "expected a but reached end of json"},
+	{"t", "expected r but reached end of json"},
+	{"n", "expected u but reached end of json"},
+	{"{\"foo\":\"bar\"} {\"foo\":\"bar\"}", "failed to consume entire json string"},
+	{"[,1]", "expected any of \"10123456789{[tfn at index 1 but read ','"},
+	{"[1,]", "expected any of \"10123456789{[tfn at index 3 but read ']'"},
+	{"[1 2]", "expected any of ,] at index 3 but read '2'"},
+	{"{,}", "expected \" at index 1 but read ','"},
+	{"{\"a\":1,}", "expected \" at index 7 but read '}'"},
+	{"{\"a\":1 \"b\":2}", "expected any of ,} at index 7 but read '\"'"},
+	{"1e", "expected any of +-0123456789 but reached end of json"},
+	{"1e+a", "expected any of 0123456789 at index 3 but read 'a'"},
+	{"\"\\\\\"\"", "failed to consume entire json string"},
+}
+
+func TestIsJsonInvalidJsons(t *testing.T) {
+	for _, testCase := range invalidJsonTestCases {
+		t.Run(
+			testCase.testJson,
+			func(t *testing.T) {
+				err := IsJson([]byte(testCase.testJson))
+				require.NotNil(t, err)
+				require.Equal(t, testCase.expectedError, err.Error())
+			},
+		)
+	}
+}
+
+func TestValidateReaderInvalidJsons(t *testing.T) {
+	for _, testCase := range invalidJsonTestCases {
+		t.Run(
+			testCase.testJson,
+			func(t *testing.T) {
+				err := ValidateReader(iotest.OneByteReader(strings.NewReader(testCase.testJson)))
+				require.NotNil(t, err)
+				require.Equal(t, testCase.expectedError, err.Error())
+			},
+		)
+	}
+}
+
+// TestJSONTestSuite checks IsJson, ValidateReader, RedactAllValues, Tokenizer and Walk against the parsing test cases
+// from https://github.com/nst/JSONTestSuite. Every y_ case must be accepted and every n_ case rejected, whilst the i_
+// cases are left to the implementation, so they are only checked for panics, except for the i_ cases of strings and
+// names with invalid UTF-8 or unpaired surrogates, which must be rejected when Options.ValidateUTF8 is set. Tokenizer
+// and Walk must also return exactly the same errors as IsJson.
+func TestJSONTestSuite(t *testing.T) {
+	testDir := "testdata/JSONTestSuite/test_parsing"
+	dirEntries, err := os.ReadDir(testDir)
+	require.Nil(t, err)
+	for _, dirEntry := range dirEntries {
+		t.Run(
+			dirEntry.Name(),
+			func(t *testing.T) {
+				testJson, err := os.ReadFile(filepath.Join(testDir, dirEntry.Name()))
+				require.Nil(t, err)
+				for _, options := range []Options{{}, {ValidateUTF8: true}} {
+					isJsonErr := options.IsJson(testJson)
+					validateReaderErr := options.ValidateReader(bytes.NewReader(testJson))
+					_, redactAllValuesErr := options.RedactAllValues(bytes.Clone(testJson))
+					_, tokenizeErr := tokenize(options, testJson)
+					require.Equal(t, isJsonErr, tokenizeErr)
+					require.Equal(t, isJsonErr, options.Walk(testJson, &recordingVisitor{}))
+					switch {
+					case strings.HasPrefix(dirEntry.Name(), "y_"):
+						require.Nil(t, isJsonErr)
+						require.Nil(t, validateReaderErr)
+						require.Nil(t, redactAllValuesErr)
+					case strings.HasPrefix(dirEntry.Name(), "n_"),
+						options.ValidateUTF8 && strings.HasPrefix(dirEntry.Name(), "i_string_"),
+						options.ValidateUTF8 && strings.HasPrefix(dirEntry.Name(), "i_object_"):
+						require.NotNil(t, isJsonErr)
+						require.NotNil(t, validateReaderErr)
+						require.NotNil(t, redactAllValuesErr)
+					}
+				}
+			},
+		)
+	}
+}
+
+func TestIsJsonValidateUTF8(t *testing.T) {
+	options := Options{ValidateUTF8: true}
+	validTestCases := []string{
+		"\"\"",
+		"\"foo\"",
+		"\"\u00e9\"",
+		"\"π€𝄞\"",
+		"\"\xf4\x8f\xbf\xbf\"",
+		"\"\\u00e9\"",
+		"\"\\uD834\\uDD1E\"",
+		"\"a\\uD834\\uDD1Eb\\n\"",
+		"{\"π\":\"\\uDBFF\\uDFFF\"}",
+	}
+	for _, testCase := range validTestCases {
+		t.Run(
+			testCase,
+			func(t *testing.T) {
+				require.Nil(t, options.IsJson([]byte(testCase)))
+				require.Nil(t, options.ValidateReader(iotest.OneByteReader(strings.NewReader(testCase))))
+			},
+		)
+	}
+	invalidTestCases := []struct {
+		testJson      string
+		expectedError string
+	}{
+		{"\"\xff\"", "invalid UTF-8 byte 0xff at index 1"},
+		{"\"\x81\"", "invalid UTF-8 byte 0x81 at index 1"},
+		{"\"\xc0\xaf\"", "invalid UTF-8 byte 0xc0 at index 1"},
+		{"\"\xe0\x80\xaf\"", "invalid UTF-8 byte 0x80 at index 2"},
+		{"\"\xed\xa0\x80\"", "invalid UTF-8 byte 0xa0 at index 2"},
+		{"\"\xf4\x90\x80\x80\"", "invalid UTF-8 byte 0x90 at index 2"},
+		{"\"\xe2\x82\"", "invalid UTF-8 byte 0x22 at index 3"},
+		{"\"\xe2\x82", "expected UTF-8 continuation byte but reached end of json"},
+		{"{\"\xe9\":0}", "invalid UTF-8 byte 0x22 at index 3"},
+		{"\"\\uD800\"", "unpaired UTF-16 surrogate escape at index 1"},
+		{"\"\\uD800abc\"", "unpaired UTF-16 surrogate escape at index 1"},
+		{"\"\\uD800\\n\"", "unpaired UTF-16 surrogate escape at index 1"},
+		{"\"\\uD800\\uD800\\uDC00\"", "unpaired UTF-16 surrogate escape at index 1"},
+		{"\"ab\\uDC00\"", "unpaired UTF-16 surrogate escape at index 3"},
+		{"{\"\\uDFAA\":0}", "unpaired UTF-16 surrogate escape at index 2"},
+	}
+	for _, testCase := range invalidTestCases {
+		t.Run(
+			testCase.testJson,
+			func(t *testing.T) {
+				err := options.IsJson([]byte(testCase.testJson))
+				require.NotNil(t, err)
+				require.Equal(t, testCase.expectedError, err.Error())
+				err = options.ValidateReader(iotest.OneByteReader(strings.NewReader(testCase.testJson)))
+				require.NotNil(t, err)
+				require.Equal(t, testCase.expectedError, err.Error())
+				_, err = options.RedactAllValues([]byte(testCase.testJson))
+				require.NotNil(t, err)
+				require.Equal(t, testCase.expectedError, err.Error())
+			},
+		)
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	testCases := []struct {
+		name          string
+		options       Options
+		testJson      string
+		expectedError string
+	}{
+		{"DefaultArrays", Options{}, strings.Repeat("[", DefaultMaxDepth) + strings.Repeat("]", DefaultMaxDepth), ""},
+		{
+			"DefaultArraysExceeded", Options{},
+			strings.Repeat("[", DefaultMaxDepth+1) + strings.Repeat("]", DefaultMaxDepth+1),
+			"maximum depth exceeded at index 10000",
+		},
+		{
+			"DefaultOpenArraysExceeded", Options{}, strings.Repeat("[", 100000),
+			"maximum depth exceeded at index 10000",
+		},
+		{
+			"Negative", Options{MaxDepth: -1},
+			strings.Repeat("[", MaxSupportedDepth) + strings.Repeat("]", MaxSupportedDepth), "",
+		},
+		{
+			"NegativeExceeded", Options{MaxDepth: -1},
+			strings.Repeat("[", MaxSupportedDepth+1) + strings.Repeat("]", MaxSupportedDepth+1),
+			"maximum depth exceeded at index 100000",
+		},
+		{
+			"AboveSupportedExceeded", Options{MaxDepth: math.MaxInt}, strings.Repeat("[", MaxSupportedDepth+1),
+			"maximum depth exceeded at index 100000",
+		},
+		{"Objects", Options{MaxDepth: 2}, "{\"foo\":{\"bar\":{}}}", "maximum depth exceeded at index 14"},
+		{"Mixed", Options{MaxDepth: 2}, "[{\"foo\":[]}]", "maximum depth exceeded at index 8"},
+		{"Siblings", Options{MaxDepth: 3}, "[[],{},[{}],{\"foo\":[]}]", ""},
+		{"Scalars", Options{MaxDepth: 1}, "[0,\"foo\",true,false,null]", ""},
+	}
+	for _, testCase := range testCases {
+		t.Run(
+			testCase.name,
+			func(t *testing.T) {
+				errs := []error{
+					testCase.options.IsJson([]byte(testCase.testJson)),
+					testCase.options.ValidateReader(strings.NewReader(testCase.testJson)),
+				}
+				_, err := testCase.options.RedactAllValues([]byte(testCase.testJson))
+				errs = append(errs, err)
+				for _, err := range errs {
+					if testCase.expectedError == "" {
+						require.Nil(t, err)
+						continue
+					}
+					var syntaxError *SyntaxError
+					require.True(t, errors.As(err, &syntaxError))
+					require.Equal(t, SyntaxErrorMaxDepthExceeded, syntaxError.Kind)
+					require.Equal(t, testCase.expectedError, err.Error())
+				}
+			},
+		)
+	}
+}
+
+func TestRedactAllValuesInvalidJsons(t *testing.T) {
+	for _, testCase := range invalidJsonTestCases {
+		t.Run(
+			testCase.testJson,
+			func(t *testing.T) {
+				_, err := RedactAllValues([]byte(testCase.testJson))
+				require.NotNil(t, err)
+				require.Equal(t, testCase.expectedError, err.Error())
+				dst := []byte("prefix")
+				redactedJson, err := RedactAllValuesTo(dst, []byte(testCase.testJson))
+				require.NotNil(t, err)
+				require.Equal(t, testCase.expectedError, err.Error())
+				require.Equal(t, dst, redactedJson)
+				_, err = RedactAllValuesInPlace([]byte(testCase.testJson))
+				require.NotNil(t, err)
+				require.Equal(t, testCase.expectedError, err.Error())
+			},
+		)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	testCases := []struct {
+		name          string
+		redactPaths   []string
+		preservePaths []string
+		testJson      string
+		expectedJson  string
+	}{
+		{"NoRules", nil, nil, ` {"a" : "b", "c" : [1, false]} `, `{"a":"b","c":[1,false]}`},
+		{"Root", []string{"$"}, nil, `{"a":"b","c":[1,false,null]}`, `{"a":"","c":[0,true,null]}`},
+		{"RootScalar", []string{"$"}, nil, `"secret"`, `""`},
+		{"Member", []string{"$.user.password"}, nil,
+			`{"user":{"id":7,"password":"hunter2"},"password":"x"}`,
+			`{"user":{"id":7,"password":""},"password":"x"}`},
+		{"Descendant", []string{"$..token"}, nil,
+			`{"token":"a","b":{"token":"b","c":[{"token":3}]},"tokens":"d"}`,
+			`{"token":"","b":{"token":"","c":[{"token":0}]},"tokens":"d"}`},
+		{"DescendantContainer", []string{"$..secret"}, nil,
+			`{"secret":{"a":"b","c":[1]},"d":{"secret":[true]}}`,
+			`{"secret":{"a":"","c":[0]},"d":{"secret":[true]}}`},
+		{"WildcardIndex", []string{"$.items[*].card.number"}, nil,
+			`{"items":[{"card":{"number":"4111","expiry":"12/30"}},{"card":{"number":"5500"}}]}`,
+			`{"items":[{"card":{"number":"","expiry":"12/30"}},{"card":{"number":""}}]}`},
+		{"WildcardMember", []string{"$.*.b"}, nil,
+			`{"x":{"b":1},"y":{"b":2,"c":3}}`,
+			`{"x":{"b":0},"y":{"b":0,"c":3}}`},
+		{"Index", []string{"$[1]"}, nil, `["a","b","c"]`, `["a","","c"]`},
+		{"DescendantIndex", []string{"$..[0]"}, nil, `[["a","b"],["c"]]`, `[["",""],[""]]`},
+		{"IndexDoesNotMatchMember", []string{"$[0]"}, nil, `{"0":"a"}`, `{"0":"a"}`},
+		{"NameDoesNotMatchElement", []string{"$['0']"}, nil, `["a"]`, `["a"]`},
+		{"QuotedName", []string{"$['a.b']", `$["c'd"]`}, nil, `{"a.b":"x","a":{"b":"y"},"c'd":1}`,
+			`{"a.b":"","a":{"b":"y"},"c'd":0}`},
+		{"EscapedName", []string{"$.pässword"}, nil, `{"p\u00e4ssword":"x","p\u00e4sswort":"y"}`,
+			`{"p\u00e4ssword":"","p\u00e4sswort":"y"}`},
+		{"Allowlist", []string{"$"}, []string{"$.id", "$..status"},
+			`{"id":42,"name":"Ann","events":[{"status":200,"at":"now"}]}`,
+			`{"id":42,"name":"","events":[{"status":200,"at":""}]}`},
+		{"NearestWins", []string{"$.a", "$.a.b.c"}, []string{"$.a.b"},
+			`{"a":{"b":{"c":"x","d":"y"},"e":"z"}}`,
+			`{"a":{"b":{"c":"","d":"y"},"e":""}}`},
+		{"PreserveWinsTies", []string{"$..id"}, []string{"$.user.id"},
+			`{"id":1,"user":{"id":2}}`,
+			`{"id":0,"user":{"id":2}}`},
+		{"Whitespace", []string{"$.b"}, nil, " { \"a\" : [ 1 , 2 ] ,\n \"b\" : { } } ", `{"a":[1,2],"b":{}}`},
+	}
+	for _, testCase := range testCases {
+		t.Run(
+			testCase.name,
+			func(t *testing.T) {
+				rules, err := CompileRedactRules(testCase.redactPaths, testCase.preservePaths)
+				require.Nil(t, err)
+				testJson := []byte(testCase.testJson)
+				redactedJson, err := Redact(testJson, rules)
+				require.Nil(t, err)
+				require.Equal(t, testCase.expectedJson, string(redactedJson))
+				require.Equal(t, testCase.testJson, string(testJson))
+			},
+		)
+	}
+}
+
+func TestRedactKeys(t *testing.T) {
+	testCases := []struct {
+		name         string
+		denylist     []string
+		allowlist    []string
+		testJson     string
+		expectedJson string
+	}{
+		{"NoPatterns", nil, nil, `{"password":"x"}`, `{"password":"x"}`},
+		{"Name", []string{"password", "ssn"}, nil,
+			`{"user":{"password":"x","name":"Ann"},"ssn":123,"items":[{"password":false}]}`,
+			`{"user":{"password":"","name":"Ann"},"ssn":0,"items":[{"password":true}]}`},
+		{"IgnoresCase", []string{"password"}, nil, `{"Password":"x","PASSWORD":"y"}`, `{"Password":"","PASSWORD":""}`},
+		{"FoldsUnicode", []string{"straße"}, nil, `{"STRASSE":"x","STRAẞE":"y"}`, `{"STRASSE":"x","STRAẞE":""}`},
+		{"WholeName", []string{"token"}, nil, `{"token":"x","tokens":"y","mytoken":"z"}`,
+			`{"token":"","tokens":"y","mytoken":"z"}`},
+		{"Star", []string{"*_token"}, nil, `{"access_token":"x","Refresh_Token":"y","_token":"z","token":"w"}`,
+			`{"access_token":"","Refresh_Token":"","_token":"","token":"w"}`},
+		{"Stars", []string{"*secret*"}, nil, `{"secret":"x","client_secret_key":"y","secre":"z"}`,
+			`{"secret":"","client_secret_key":"","secre":"z"}`},
+		{"QuestionMark", []string{"pin?"}, nil, `{"pin1":1,"pin":2,"pin12":3,"pinü":4}`,
+			`{"pin1":0,"pin":2,"pin12":3,"pinü":0}`},
+		{"EscapedName", []string{"password"}, nil, `{"pass\u0077ord":"x"}`, `{"pass\u0077ord":""}`},
+		{"Descendants", []string{"credentials"}, nil, `{"credentials":{"user":"a","keys":[1,{"b":true}]},"c":"d"}`,
+			`{"credentials":{"user":"","keys":[0,{"b":true}]},"c":"d"}`},
+		{"Allowlist", nil, []string{"id", "*_at"}, `{"id":1,"name":"Ann","created_at":"now","tags":["a"]}`,
+			`{"id":1,"name":"","created_at":"now","tags":[""]}`},
+		{"AllowlistRootScalar", nil, []string{"id"}, `"x"`, `""`},
+		{"NearestWins", []string{"secret*"}, []string{"public"},
+			`{"secrets":{"public":{"key":"a","secret_key":"b"},"private":"c"}}`,
+			`{"secrets":{"public":{"key":"a","secret_key":""},"private":""}}`},
+		{"AllowlistWinsTies", []string{"*id"}, []string{"id"}, `{"id":1,"uid":2}`, `{"id":1,"uid":0}`},
+	}
+	for _, testCase := range testCases {
+		t.Run(
+			testCase.name,
+			func(t *testing.T) {
+				rules, err := CompileKeyRedactRules(testCase.denylist, testCase.allowlist)
+				require.Nil(t, err)
+				redactedJson, err := Redact([]byte(testCase.testJson), rules)
+				require.Nil(t, err)
+				require.Equal(t, testCase.expectedJson, string(redactedJson))
+			},
+		)
+	}
+	_, err := CompileKeyRedactRules([]string{"a", ""}, nil)
+	require.NotNil(t, err)
+	require.Equal(t, `invalid name pattern "": expected at least one character`, err.Error())
+}
+
+func TestRedactor(t *testing.T) {
+	rules, err := CompileRedactRules([]string{"$.secret"}, nil)
+	require.Nil(t, err)
+	testCases := []struct {
+		name         string
+		redactor     Redactor
+		testJson     string
+		expectedJson string
+	}{
+		{"ZeroValue", Redactor{}, `{"a":"b","c":[1,false,null]}`, `{"a":"","c":[0,true,null]}`},
+		{"Keep", Redactor{Strings: Keep, Numbers: Keep, Booleans: Keep, Nulls: Keep},
+			` {"a" : "b", "c" : [1, false, null]} `, `{"a":"b","c":[1,false,null]}`},
+		{"Marker", Redactor{Strings: Marker("[REDACTED]"), Numbers: Marker("[REDACTED]"), Nulls: Marker(`"\`)},
+			`{"a":"b","c":[1,null]}`, `{"a":"[REDACTED]","c":["[REDACTED]","\"\\"]}`},
+		{"MaskString", Redactor{Strings: MaskString('*')}, `["hunter2","","\u00e9t\u00e9","\ud83d\ude00"]`,
+			`["*******","","***","*"]`},
+		{"MaskStringEscapedMask", Redactor{Strings: MaskString('"')}, `"ab"`, `"\"\""`},
+		{"MaskStringNumber", Redactor{Numbers: MaskString('#')}, `[12345,-1.5]`, `["#####","####"]`},
+		{"KeepEnds", Redactor{Strings: KeepEnds(4, 4), Numbers: KeepEnds(0, 2)},
+			`["4111111111111111","12345678","\"quoted\" and \\","ab",4111111111]`,
+			`["4111********1111","********","\"quo******nd \\","**","********11"]`},
+		{"KeepEndsNegative", Redactor{Strings: KeepEnds(-1, 1)}, `"abc"`, `"**c"`},
+		{"Booleans", Redactor{Booleans: Keep}, `[true,false,"a"]`, `[true,false,""]`},
+		{"Rules", Redactor{Rules: &rules, Strings: Marker("x")}, `{"secret":"a","b":"c"}`, `{"secret":"x","b":"c"}`},
+	}
+	for _, testCase := range testCases {
+		t.Run(
+			testCase.name,
+			func(t *testing.T) {
+				redactedJson, err := testCase.redactor.Redact([]byte("prefix"), []byte(testCase.testJson))
+				require.Nil(t, err)
+				require.Equal(t, "prefix"+testCase.expectedJson, string(redactedJson))
+				require.Nil(t, IsJson(redactedJson[len("prefix"):]))
+			},
+		)
+	}
+	dst := []byte("prefix")
+	redactedJson, err := Redactor{Strings: Marker("x")}.Redact(dst, []byte(`["a",]`))
+	require.NotNil(t, err)
+	require.Equal(t, "expected any of \"10123456789{[tfn at index 5 but read ']'", err.Error())
+	require.Equal(t, dst, redactedJson)
+	// Strategies which could lengthen values must not overwrite the JSON before it is read.
+	src := []byte(`["a",1]`)
+	redactedJson, err = Redactor{Strings: Marker("[REDACTED]")}.Redact(src[:0], src)
+	require.ErrorIs(t, err, ErrBuffersOverlap)
+	require.Equal(t, 0, len(redactedJson))
+	require.Equal(t, `["a",1]`, string(src))
+	src = []byte(` {"a" : "b", "c" : [1, false, null]} `)
+	redactedJson, err = Redactor{Booleans: Keep}.Redact(src[:0], src)
+	require.Nil(t, err)
+	require.Equal(t, `{"a":"","c":[0,false,null]}`, string(redactedJson))
+}
+
+func TestRedactorPreserveWhitespace(t *testing.T) {
+	rules, err := CompileKeyRedactRules([]string{"password", "*_token"}, nil)
+	require.Nil(t, err)
+	testJson := "\n{\n  \"user\": \"ann\",\n  \"password\" : \"hunter2\",\r\n\t\"retries\":3,\n  " +
+		"\"auth\": {\n    \"access_token\": \"abc\",\n    \"scopes\": [ \"read\" , \"write\" ]\n  },\n" +
+		"  \"empty\": [ ],\n  \"none\": { }\n}\n"
+	expectedJson := "\n{\n  \"user\": \"ann\",\n  \"password\" : \"*******\",\r\n\t\"retries\":3,\n  " +
+		"\"auth\": {\n    \"access_token\": \"***\",\n    \"scopes\": [ \"read\" , \"write\" ]\n  },\n" +
+		"  \"empty\": [ ],\n  \"none\": { }\n}\n"
+	redactor := Redactor{Rules: &rules, Strings: MaskString('*'), PreserveWhitespace: true}
+	redactedJson, err := redactor.Redact(nil, []byte(testJson))
+	require.Nil(t, err)
+	require.Equal(t, expectedJson, string(redactedJson))
+	redactedJson, err = Redactor{PreserveWhitespace: true}.Redact(nil, []byte(" [ 1 ,\n\t\"a\" , true , null ] "))
+	require.Nil(t, err)
+	require.Equal(t, " [ 0 ,\n\t\"\" , true , null ] ", string(redactedJson))
+	redactedJson, err = Redactor{PreserveWhitespace: true}.Redact(nil, []byte("[1,\n2,\n3,]"))
+	require.NotNil(t, err)
+	require.Nil(t, redactedJson)
+	var syntaxError *SyntaxError
+	require.True(t, errors.As(err, &syntaxError))
+	require.Equal(t, 3, syntaxError.Line)
+}
+
+func TestNumberMagnitude(t *testing.T) {
+	testCases := []struct {
+		testJson     string
+		expectedJson string
+	}{
+		{"0", "0"},
+		{"-0", "0"},
+		{"0.000", "0"},
+		{"0e10", "0"},
+		{"1", "1"},
+		{"9", "1"},
+		{"-7", "-1"},
+		{"10", "10"},
+		{"1234.5", "1000"},
+		{"-99999", "-10000"},
+		{"0.5", "0.1"},
+		{"-0.05", "-0.01"},
+		{"0.000001", "0.000001"},
+		{"0.0000001", "1e-7"},
+		{"6.02e23", "1e23"},
+		{"6.02E+20", "100000000000000000000"},
+		{"12e-3", "0.01"},
+		{"1.5e-400", "1e-400"},
+		{"1e99999999999999999999", "1e100000000"},
+		{"123456789012345678901234567890", "1e29"},
+		{`"1"`, "0"},
+		{"true", "0"},
+	}
+	for _, testCase := range testCases {
+		t.Run(
+			testCase.testJson,
+			func(t *testing.T) {
+				redactedJson := NumberMagnitude.AppendRedacted(nil, []byte(testCase.testJson))
+				require.Equal(t, testCase.expectedJson, string(redactedJson))
+			},
+		)
+	}
+}
+
+func TestHMAC(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	token := func(value string) string {
+		hmacHash := hmac.New(sha256.New, key)
+		hmacHash.Write([]byte(value))
+		return `"h:` + hex.EncodeToString(hmacHash.Sum(nil)[:HMACTokenSize]) + `"`
+	}
+	redactor := Redactor{Strings: HMAC(key), Numbers: HMAC(key)}
+	redactedJson, err := redactor.Redact(nil, []byte(`{"email":"ann@example.com","id":42,"alias":"ann@example.com"}`))
+	require.Nil(t, err)
+	require.Equal(
+		t,
+		`{"email":`+token("ann@example.com")+`,"id":`+token("42")+`,"alias":`+token("ann@example.com")+`}`,
+		string(redactedJson),
+	)
+	redactedJson, err = redactor.Redact(nil, []byte(`["\u0061\n","a\n","é","\u00e9","1","1.0"]`))
+	require.Nil(t, err)
+	require.Equal(
+		t,
+		"["+token("a\n")+","+token("a\n")+","+token("é")+","+token("é")+","+token("1")+","+token("1.0")+"]",
+		string(redactedJson),
+	)
+	otherKeyRedactedJson, err := Redactor{Strings: HMAC([]byte("another key"))}.Redact(nil, []byte(`"a\n"`))
+	require.Nil(t, err)
+	require.NotEqual(t, token("a\n"), string(otherKeyRedactedJson))
+}
+
+func TestRedactInvalidJsons(t *testing.T) {
+	rules, err := CompileRedactRules([]string{"$..a"}, []string{"$..b"})
+	require.Nil(t, err)
+	for _, testCase := range invalidJsonTestCases {
+		t.Run(
+			testCase.testJson,
+			func(t *testing.T) {
+				_, err := Redact([]byte(testCase.testJson), rules)
+				require.NotNil(t, err)
+				require.Equal(t, testCase.expectedError, err.Error())
+			},
+		)
+	}
+}
+
+func TestCompileRedactRules(t *testing.T) {
+	testCases := []struct {
+		path          string
+		expectedError string
+	}{
+		{"$", ""},
+		{"$.a.b", ""},
+		{"$..a", ""},
+		{"$[0][12]", ""},
+		{"$.*[*]..*", ""},
+		{"$['a]b']", ""},
+		{`$['a\'b']`, ""},
+		{"", `invalid path "": expected $ at index 0`},
+		{"a.b", `invalid path "a.b": expected $ at index 0`},
+		{"$a", `invalid path "$a": expected any of .[ at index 1`},
+		{"$.", `invalid path "$.": expected a name at index 2`},
+		{"$..", `invalid path "$..": expected a name at index 3`},
+		{"$.a.", `invalid path "$.a.": expected a name at index 4`},
+		{"$[0", `invalid path "$[0": expected ] but reached end of path`},
+		{"$[a]", `invalid path "$[a]": expected an array index at index 2`},
+		{"$[-1]", `invalid path "$[-1]": expected an array index at index 2`},
+		{"$[+1]", `invalid path "$[+1]": expected an array index at index 2`},
+		{"$['a", `invalid path "$['a": expected ' but reached end of path`},
+		{"$['a'", `invalid path "$['a'": expected ] at index 5`},
+	}
+	for _, testCase := range testCases {
+		t.Run(
+			testCase.path,
+			func(t *testing.T) {
+				_, err := CompileRedactRules(nil, []string{testCase.path})
+				if testCase.expectedError == "" {
+					require.Nil(t, err)
+				} else {
+					require.NotNil(t, err)
+					require.Equal(t, testCase.expectedError, err.Error())
+				}
+			},
+		)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	compactTestCases := slices.Clone(validJsonTestCases)
+	compactTestCases = append(compactTestCases, " {\n\t\"a b\" : [ 1 , \"c d\" ] ,\r\n \"e\":{ } } ")
+	for _, testCase := range testJsonCases {
+		compactTestCases = append(compactTestCases, string(*testCase.testJson))
+	}
+	for _, testCase := range compactTestCases {
+		t.Run(
+			testCase[:min(len(testCase), 64)],
+			func(t *testing.T) {
+				var expectedJson bytes.Buffer
+				require.Nil(t, json.Compact(&expectedJson, []byte(testCase)))
+				testJson := []byte(testCase)
+				compactedJson, err := Compact([]byte("prefix"), testJson)
+				require.Nil(t, err)
+				require.Equal(t, "prefix"+expectedJson.String(), string(compactedJson))
+				require.Equal(t, testCase, string(testJson))
+				compactedJson, err = CompactInPlace(testJson)
+				require.Nil(t, err)
+				require.Equal(t, expectedJson.String(), string(compactedJson))
+				require.Equal(t, &testJson[0], &compactedJson[0])
+			},
+		)
+	}
+}
+
+func TestCompactInvalidJsons(t *testing.T) {
+	for _, testCase := range invalidJsonTestCases {
+		t.Run(
+			testCase.testJson,
+			func(t *testing.T) {
+				dst := []byte("prefix")
+				compactedJson, err := Compact(dst, []byte(testCase.testJson))
+				require.NotNil(t, err)
+				require.Equal(t, testCase.expectedError, err.Error())
+				require.Equal(t, dst, compactedJson)
+				_, err = CompactInPlace([]byte(testCase.testJson))
+				require.NotNil(t, err)
+				require.Equal(t, testCase.expectedError, err.Error())
+			},
+		)
+	}
+}
+
+func TestRedactAndCompactAllocations(t *testing.T) {
+	testJson := make([]byte, len(packageLockAxios))
+	dst := make([]byte, 0, len(packageLockAxios))
+	var err error
+	testCases := []struct {
+		name           string
+		function       func()
+		expectedAllocs float64
+	}{
+		{
+			"RedactAllValuesInPlace",
+			func() {
+				copy(testJson, packageLockAxios)
+				_, err = RedactAllValuesInPlace(testJson)
+			},
+			0,
+		},
+		{
+			"CompactInPlace",
+			func() {
+				copy(testJson, packageLockAxios)
+				_, err = CompactInPlace(testJson)
+			},
+			0,
+		},
+		{"RedactAllValuesTo", func() { _, err = RedactAllValuesTo(dst[:0], packageLockAxios) }, 0},
+		{"Compact", func() { _, err = Compact(dst[:0], packageLockAxios) }, 0},
+		{"RedactAllValues", func() { _, err = RedactAllValues(packageLockAxios) }, 1},
+	}
+	for _, testCase := range testCases {
+		t.Run(
+			testCase.name,
+			func(t *testing.T) {
+				allocs := testing.AllocsPerRun(10, testCase.function)
+				require.Nil(t, err)
+				require.Equal(t, testCase.expectedAllocs, allocs)
+			},
+		)
+	}
+}
+
+func TestIndent(t *testing.T) {
+	indentTestCases := slices.Clone(validJsonTestCases)
+	indentTestCases = append(
+		indentTestCases, " {\n\t\"a b\" : [ 1 , \"c d\", [], {} ] ,\r\n \"e\":{ \"f\": [[null]] } } ",
+	)
+	for _, testCase := range testJsonCases {
+		indentTestCases = append(indentTestCases, string(*testCase.testJson))
+	}
+	for _, testCase := range indentTestCases {
+		t.Run(
+			testCase[:min(len(testCase), 64)],
+			func(t *testing.T) {
+				var expectedJson bytes.Buffer
+				// encoding/json.Indent copies trailing whitespace, whereas Indent does not.
+				require.Nil(t, json.Indent(&expectedJson, bytes.TrimRight([]byte(testCase), " \t\r\n"), "> ", "\t"))
+				indentedJson, err := Indent([]byte("prefix"), []byte(testCase), "> ", "\t")
+				require.Nil(t, err)
+				require.Equal(t, "prefix"+expectedJson.String(), string(indentedJson))
+			},
+		)
+	}
+}
+
+func TestIndentOptions(t *testing.T) {
+	testCases := []struct {
+		name          string
+		indentOptions IndentOptions
+		testJson      string
+		expectedJson  string
+	}{
+		{"SortKeys", IndentOptions{Indentation: "  ", SortKeys: true},
+			`{"b":1,"a":{"d":[{"z":0,"y":0}],"c":2},"\u0061a":3,"B":4,"é":5}`,
+			"{\n  \"B\": 4,\n  \"a\": {\n    \"c\": 2,\n    \"d\": [\n      {\n        \"y\": 0,\n        \"z\": 0\n" +
+				"      }\n    ]\n  },\n  \"\\u0061a\": 3,\n  \"b\": 1,\n  \"é\": 5\n}"},
+		{"SortKeysStable", IndentOptions{SortKeys: true}, `{"b":1,"a":2,"b":3,"\u0061":4}`,
+			"{\n\"a\": 2,\n\"\\u0061\": 4,\n\"b\": 1,\n\"b\": 3\n}"},
+		{"SortKeysSorted", IndentOptions{SortKeys: true}, `{"a":1,"b":2}`, "{\n\"a\": 1,\n\"b\": 2\n}"},
+		{"SortKeysEmpty", IndentOptions{SortKeys: true}, `{ }`, "{}"},
+		{"CompactArrays", IndentOptions{Indentation: "  ", CompactArrays: true},
+			`{"a":[1, "two",true,null , {}],"b":[ 1,2 ,3],"c":[[1],[]],"d":[ ]}`,
+			"{\n  \"a\": [\n    1,\n    \"two\",\n    true,\n    null,\n    {}\n  ],\n  \"b\": [1, 2, 3],\n" +
+				"  \"c\": [\n    [1],\n    []\n  ],\n  \"d\": []\n}"},
+		{"MaxWidth", IndentOptions{Prefix: "#", Indentation: "  ", CompactArrays: true, MaxWidth: 20},
+			`{"a":[1,2,3,4],"b":[1,2,3,4,5],"c":[[1,2,3,4,5]]}`,
+			"{\n#  \"a\": [1, 2, 3, 4],\n#  \"b\": [\n#    1,\n#    2,\n#    3,\n#    4,\n#    5\n#  ],\n" +
+				"#  \"c\": [\n#    [1, 2, 3, 4, 5]\n#  ]\n#}"},
+		{"MaxWidthRoot", IndentOptions{CompactArrays: true, MaxWidth: 4}, `[1,2]`, "[\n1,\n2\n]"},
+	}
+	for _, testCase := range testCases {
+		t.Run(
+			testCase.name,
+			func(t *testing.T) {
+				indentedJson, err := testCase.indentOptions.Indent(nil, []byte(testCase.testJson))
+				require.Nil(t, err)
+				require.Equal(t, testCase.expectedJson, string(indentedJson))
+			},
+		)
+	}
+}
+
+func TestIndentInvalidJsons(t *testing.T) {
+	indentOptions := []IndentOptions{{}, {SortKeys: true, CompactArrays: true, MaxWidth: 2}}
+	for _, testCase := range invalidJsonTestCases {
+		t.Run(
+			testCase.testJson,
+			func(t *testing.T) {
+				for _, indentOptions := range indentOptions {
+					dst := []byte("prefix")
+					indentedJson, err := indentOptions.Indent(dst, []byte(testCase.testJson))
+					require.NotNil(t, err)
+					require.Equal(t, testCase.expectedError, err.Error())
+					require.Equal(t, dst, indentedJson)
+				}
+			},
+		)
+	}
+	// Laying out an array over multiple lines after it turns out to be too wide must not lose track of the depth.
+	indentOptions = []IndentOptions{
+		{Options: Options{MaxDepth: 2}},
+		{CompactArrays: true, MaxWidth: 5, Options: Options{MaxDepth: 2}},
+	}
+	for _, indentOptions := range indentOptions {
+		_, err := indentOptions.Indent(nil, []byte("[[1,2],[[3]]]"))
+		require.NotNil(t, err)
+		require.Equal(t, "maximum depth exceeded at index 8", err.Error())
+	}
+}
+
+func TestGet(t *testing.T) {
+	testJson := ` { "type" : "order", "id": 42, "items": [ {"sku": "a", "qty": 1.5e2 }, {"sku": "b", "tags": [ ] } ],
+		"paid": false, "note": null, "nested": {"a": {"b": {"c": true}}}, "p\u00e4th": "escaped", "dup": 1, "dup": 2,
+		"\ud83d\ude00": "emoji", "": "empty", "a\"b": "quote" } `
+	testCases := []struct {
+		path          []string
+		expectedValue string
+		expectedKind  Kind
+		expectedError error
+	}{
+		{nil, testJson[1 : len(testJson)-1], KindObject, nil},
+		{[]string{"type"}, `"order"`, KindString, nil},
+		{[]string{"id"}, "42", KindNumber, nil},
+		{[]string{"items"}, `[ {"sku": "a", "qty": 1.5e2 }, {"sku": "b", "tags": [ ] } ]`, KindArray, nil},
+		{[]string{"items", "0"}, `{"sku": "a", "qty": 1.5e2 }`, KindObject, nil},
+		{[]string{"items", "0", "qty"}, "1.5e2", KindNumber, nil},
+		{[]string{"items", "1", "sku"}, `"b"`, KindString, nil},
+		{[]string{"items", "1", "tags"}, "[ ]", KindArray, nil},
+		{[]string{"paid"}, "false", KindBoolean, nil},
+		{[]string{"note"}, "null", KindNull, nil},
+		{[]string{"nested", "a", "b", "c"}, "true", KindBoolean, nil},
+		{[]string{"päth"}, `"escaped"`, KindString, nil},
+		{[]string{"dup"}, "1", KindNumber, nil},
+		{[]string{"😀"}, `"emoji"`, KindString, nil},
+		{[]string{""}, `"empty"`, KindString, nil},
+		{[]string{`a"b`}, `"quote"`, KindString, nil},
+		{[]string{"missing"}, "", KindInvalid, ErrPathNotFound},
+		{[]string{"pä"}, "", KindInvalid, ErrPathNotFound},
+		{[]string{"päthx"}, "", KindInvalid, ErrPathNotFound},
+		{[]string{"type", "a"}, "", KindInvalid, ErrPathNotFound},
+		{[]string{"items", "2"}, "", KindInvalid, ErrPathNotFound},
+		{[]string{"items", "-1"}, "", KindInvalid, ErrPathNotFound},
+		{[]string{"items", "01"}, "", KindInvalid, ErrPathNotFound},
+		{[]string{"items", "sku"}, "", KindInvalid, ErrPathNotFound},
+		{[]string{"items", "1", "tags", "0"}, "", KindInvalid, ErrPathNotFound},
+		{[]string{"nested", "a", "x"}, "", KindInvalid, ErrPathNotFound},
+	}
+	for _, testCase := range testCases {
+		t.Run(
+			strings.Join(testCase.path, "/"),
+			func(t *testing.T) {
+				value, kind, err := Get([]byte(testJson), testCase.path...)
+				require.Equal(t, testCase.expectedError, err)
+				require.Equal(t, testCase.expectedValue, string(value))
+				require.Equal(t, testCase.expectedKind, kind)
+			},
+		)
+	}
+	// Only as much of the JSON as is needed is read.
+	value, kind, err := Get([]byte(`{"type":"a","b":[}`), "type")
+	require.Nil(t, err)
+	require.Equal(t, `"a"`, string(value))
+	require.Equal(t, KindString, kind)
+	_, _, err = Get([]byte(`{"a":[1,}],"type":"a"}`), "type")
+	require.NotNil(t, err)
+	require.Equal(t, "expected any of \"10123456789{[tfn at index 8 but read '}'", err.Error())
+	_, _, err = Get([]byte(`{"type":tru}`), "type")
+	require.NotNil(t, err)
+	require.Equal(t, "expected e at index 11 but read '}'", err.Error())
+	_, _, err = Get([]byte(`{"type":`), "type")
+	require.NotNil(t, err)
+	require.Equal(t, "read head ran out of json", err.Error())
+	allocs := testing.AllocsPerRun(100, func() {
+		value, _, err = Get(packageLockAxios, "packages", "node_modules/yargs", "dependencies", "y18n")
+	})
+	require.Nil(t, err)
+	require.Equal(t, `"^5.0.5"`, string(value))
+	require.Equal(t, 0.0, allocs)
+}
+
+func TestGetMany(t *testing.T) {
+	testJson := []byte(
+		` {"a": {"b": [1, {"c": "d"}, [true]], "e": null}, "f": "g", "a": {"h": 0}, "i": [], "j\u006b": 1} `,
+	)
+	paths := [][]string{
+		{"f"},
+		{"a", "b", "1", "c"},
+		{"a", "b"},
+		{"a", "b", "2", "0"},
+		{"a"},
+		{"a", "h"},
+		{"a", "e"},
+		{"a", "b", "3"},
+		{"a", "b", "0", "x"},
+		{"i"},
+		{"i", "0"},
+		{"jk"},
+		{"f"},
+		{},
+		{"missing"},
+	}
+	values, err := GetMany(testJson, paths)
+	require.Nil(t, err)
+	require.Len(t, values, len(paths))
+	for pathIndex, path := range paths {
+		expectedValue, _, err := Get(testJson, path...)
+		if err != nil {
+			require.Equal(t, ErrPathNotFound, err)
+			require.Nil(t, values[pathIndex], path)
+		} else {
+			require.Equal(t, string(expectedValue), string(values[pathIndex]), path)
+		}
+	}
+	values, err = GetMany(testJson, nil)
+	require.Nil(t, err)
+	require.Len(t, values, 0)
+	// Reading stops once every value has been found.
+	values, err = GetMany([]byte(`{"a":{"b":1,"c":2},"d":[}`), [][]string{{"a", "c"}, {"a", "b"}})
+	require.Nil(t, err)
+	require.Equal(t, []string{"2", "1"}, []string{string(values[0]), string(values[1])})
+	_, err = GetMany([]byte(`{"a":{"b":1,"c":2},"d":[}`), [][]string{{"a", "c"}, {"d"}})
+	require.NotNil(t, err)
+	require.Equal(t, "expected any of \"10123456789{[tfn at index 24 but read '}'", err.Error())
+	var packageLockPaths [][]string
+	for _, dependency := range []string{"y18n", "cliui", "escalade", "string-width"} {
+		dependencyPath := []string{"packages", "node_modules/yargs", "dependencies", dependency}
+		packageLockPaths = append(packageLockPaths, dependencyPath)
+	}
+	packageLockPaths = append(packageLockPaths, []string{"name"}, []string{"packages", "", "version"})
+	values, err = GetMany(packageLockAxios, packageLockPaths)
+	require.Nil(t, err)
+	for pathIndex, path := range packageLockPaths {
+		expectedValue, _, err := Get(packageLockAxios, path...)
+		require.Nil(t, err)
+		require.Equal(t, string(expectedValue), string(values[pathIndex]))
+	}
+}
+
+func TestGetManyInvalidJsons(t *testing.T) {
+	for _, testCase := range invalidJsonTestCases {
+		t.Run(
+			testCase.testJson,
+			func(t *testing.T) {
+				_, err := GetMany([]byte(testCase.testJson), [][]string{{"a"}, {"0"}})
+				if err == nil {
+					// GetMany does not read past the value, so trailing data is not detected.
+					require.Equal(t, "failed to consume entire json string", testCase.expectedError)
+					return
+				}
+				require.Equal(t, testCase.expectedError, err.Error())
+			},
+		)
+	}
+}
+
+func TestGetInvalidJsons(t *testing.T) {
+	for _, testCase := range invalidJsonTestCases {
+		t.Run(
+			testCase.testJson,
+			func(t *testing.T) {
+				_, kind, err := Get([]byte(testCase.testJson))
+				if err == nil {
+					// Get does not read past the value, so trailing data is not detected.
+					require.Equal(t, "failed to consume entire json string", testCase.expectedError)
+					return
+				}
+				require.Equal(t, testCase.expectedError, err.Error())
+				require.Equal(t, KindInvalid, kind)
+			},
+		)
+	}
+}
+
+func TestSet(t *testing.T) {
+	testJson := ` {"a": 1, "b": {"c": [1, 2], "d": {}}, "a": 3, "ef": "x"} `
+	testCases := []struct {
+		path          []string
+		value         string
+		expectedJson  string
+		expectedError error
+	}{
+		{nil, ` [true] `, ` [true] `, nil},
+		{[]string{"a"}, "2", ` {"a": 2, "b": {"c": [1, 2], "d": {}}, "a": 3, "ef": "x"} `, nil},
+		{
+			[]string{"b", "c", "1"}, ` {"x" : null} `,
+			` {"a": 1, "b": {"c": [1, {"x" : null}], "d": {}}, "a": 3, "ef": "x"} `, nil,
+		},
+		{[]string{"ef"}, `"y"`, ` {"a": 1, "b": {"c": [1, 2], "d": {}}, "a": 3, "ef": "y"} `, nil},
+		{[]string{"g"}, "4", ` {"a": 1, "b": {"c": [1, 2], "d": {}}, "a": 3, "ef": "x","g":4} `, nil},
+		{
+			[]string{"b", "d", "h\n\""}, "[]",
+			` {"a": 1, "b": {"c": [1, 2], "d": {"h\n\"":[]}}, "a": 3, "ef": "x"} `, nil,
+		},
+		{[]string{"b", "e"}, "5", ` {"a": 1, "b": {"c": [1, 2], "d": {},"e":5}, "a": 3, "ef": "x"} `, nil},
+		{[]string{"b", "c", "2"}, "3", "", ErrPathNotFound},
+		{[]string{"x", "y"}, "3", "", ErrPathNotFound},
+		{[]string{"a", "y"}, "3", "", ErrPathNotFound},
+	}
+	for _, testCase := range testCases {
+		t.Run(
+			strings.Join(testCase.path, "/"),
+			func(t *testing.T) {
+				input := []byte(testJson)
+				output, err := Set(input, testCase.path, []byte(testCase.value))
+				require.Equal(t, testCase.expectedError, err)
+				require.Equal(t, testCase.expectedJson, string(output))
+				require.Equal(t, testJson, string(input))
+			},
+		)
+	}
+	_, err := Set([]byte(`{"a":1}`), []string{"a"}, []byte(`[1,]`))
+	require.NotNil(t, err)
+	require.Equal(t, "invalid value: expected any of \"10123456789{[tfn at index 3 but read ']'", err.Error())
+	var syntaxError *SyntaxError
+	require.ErrorAs(t, err, &syntaxError)
+	_, err = Options{MaxDepth: 1}.Set([]byte(`{"a":1}`), []string{"a"}, []byte(`[[1]]`))
+	require.NotNil(t, err)
+}
+
+func TestDelete(t *testing.T) {
+	testJson := ` { "a" : 1 , "b": [ 1, [2], 3 ], "c": {"d": null}, "a": 4 } `
+	testCases := []struct {
+		path          []string
+		expectedJson  string
+		expectedError error
+	}{
+		{[]string{"a"}, ` { "b": [ 1, [2], 3 ], "c": {"d": null}, "a": 4 } `, nil},
+		{[]string{"b"}, ` { "a" : 1, "c": {"d": null}, "a": 4 } `, nil},
+		{[]string{"b", "0"}, ` { "a" : 1 , "b": [ [2], 3 ], "c": {"d": null}, "a": 4 } `, nil},
+		{[]string{"b", "1"}, ` { "a" : 1 , "b": [ 1, 3 ], "c": {"d": null}, "a": 4 } `, nil},
+		{[]string{"b", "2"}, ` { "a" : 1 , "b": [ 1, [2] ], "c": {"d": null}, "a": 4 } `, nil},
+		{[]string{"b", "1", "0"}, ` { "a" : 1 , "b": [ 1, [], 3 ], "c": {"d": null}, "a": 4 } `, nil},
+		{[]string{"c", "d"}, ` { "a" : 1 , "b": [ 1, [2], 3 ], "c": {}, "a": 4 } `, nil},
+		{nil, "", ErrPathNotFound},
+		{[]string{"e"}, "", ErrPathNotFound},
+		{[]string{"b", "3"}, "", ErrPathNotFound},
+		{[]string{"c", "d", "e"}, "", ErrPathNotFound},
+	}
+	for _, testCase := range testCases {
+		t.Run(
+			strings.Join(testCase.path, "/"),
+			func(t *testing.T) {
+				input := []byte(testJson)
+				output, err := Delete(input, testCase.path)
+				require.Equal(t, testCase.expectedError, err)
+				require.Equal(t, testCase.expectedJson, string(output))
+				require.Equal(t, testJson, string(input))
+				if err == nil {
+					require.Nil(t, IsJson(output))
+				}
+			},
+		)
+	}
+}
+
+func TestArrayAppend(t *testing.T) {
+	testJson := `{"a": [1, 2 ], "b": [], "c": [ ], "d": {"e": [[]]}, "f": "[]"}`
+	testCases := []struct {
+		path          []string
+		value         string
+		expectedJson  string
+		expectedError string
+	}{
+		{[]string{"a"}, "3", `{"a": [1, 2,3 ], "b": [], "c": [ ], "d": {"e": [[]]}, "f": "[]"}`, ""},
+		{[]string{"b"}, ` {"x": 1} `, `{"a": [1, 2 ], "b": [{"x": 1}], "c": [ ], "d": {"e": [[]]}, "f": "[]"}`, ""},
+		{[]string{"c"}, "null", `{"a": [1, 2 ], "b": [], "c": [null ], "d": {"e": [[]]}, "f": "[]"}`, ""},
+		{[]string{"d", "e", "0"}, `"x"`, `{"a": [1, 2 ], "b": [], "c": [ ], "d": {"e": [["x"]]}, "f": "[]"}`, ""},
+		{[]string{"d", "e"}, "[]", `{"a": [1, 2 ], "b": [], "c": [ ], "d": {"e": [[],[]]}, "f": "[]"}`, ""},
+		{[]string{"d"}, "1", "", "expected an array at path but found object"},
+		{[]string{"f"}, "1", "", "expected an array at path but found string"},
+		{[]string{"g"}, "1", "", "path not found"},
+		{[]string{"a"}, "1 2", "", "invalid value: failed to consume entire json string"},
+	}
+	for _, testCase := range testCases {
+		t.Run(
+			strings.Join(testCase.path, "/"),
+			func(t *testing.T) {
+				input := []byte(testJson)
+				output, err := ArrayAppend(input, testCase.path, []byte(testCase.value))
+				if testCase.expectedError != "" {
+					require.NotNil(t, err)
+					require.Equal(t, testCase.expectedError, err.Error())
+				} else {
+					require.Nil(t, err)
+				}
+				require.Equal(t, testCase.expectedJson, string(output))
+				require.Equal(t, testJson, string(input))
+			},
+		)
+	}
+	output, err := ArrayAppend([]byte(" [\n] "), nil, []byte("1"))
+	require.Nil(t, err)
+	require.Equal(t, " [1\n] ", string(output))
+}
+
+func TestEditInvalidJsons(t *testing.T) {
+	for _, testCase := range invalidJsonTestCases {
+		t.Run(
+			testCase.testJson,
+			func(t *testing.T) {
+				_, err := Set([]byte(testCase.testJson), []string{"a"}, []byte("1"))
+				require.NotNil(t, err)
+				require.Equal(t, testCase.expectedError, err.Error())
+				_, err = Delete([]byte(testCase.testJson), []string{"a"})
+				require.NotNil(t, err)
+				require.Equal(t, testCase.expectedError, err.Error())
+				_, err = ArrayAppend([]byte(testCase.testJson), nil, []byte("1"))
+				require.NotNil(t, err)
+				require.Equal(t, testCase.expectedError, err.Error())
+			},
+		)
+	}
+}
+
+// tokenize returns every token of testJson, and the error which ended them, or nil if it was io.EOF.
+func tokenize(options Options, testJson []byte) ([]Token, error) {
+	tokenizer := options.NewTokenizer(testJson)
+	var tokens []Token
+	for {
+		token, err := tokenizer.Next()
+		if err == io.EOF {
+			return tokens, nil
+		}
+		if err != nil {
+			return tokens, err
+		}
+		tokens = append(tokens, token)
+	}
+}
+
+func TestTokenizer(t *testing.T) {
+	testCases := []struct {
+		testJson       string
+		expectedTokens []Token
+	}{
+		{"1", []Token{{TokenNumber, []byte("1")}}},
+		{` "a\"b" `, []Token{{TokenString, []byte(`"a\"b"`)}}},
+		{" [ ] ", []Token{{TokenArrayStart, []byte("[")}, {TokenArrayEnd, []byte("]")}}},
+		{"{}", []Token{{TokenObjectStart, []byte("{")}, {TokenObjectEnd, []byte("}")}}},
+		{
+			`{"a" : [true, false ,null, -1.5e3], "b": {"c": "d"}, "": []}`,
+			[]Token{
+				{TokenObjectStart, []byte("{")},
+				{TokenName, []byte(`"a"`)},
+				{TokenArrayStart, []byte("[")},
+				{TokenTrue, []byte("true")},
+				{TokenFalse, []byte("false")},
+				{TokenNull, []byte("null")},
+				{TokenNumber, []byte("-1.5e3")},
+				{TokenArrayEnd, []byte("]")},
+				{TokenName, []byte(`"b"`)},
+				{TokenObjectStart, []byte("{")},
+				{TokenName, []byte(`"c"`)},
+				{TokenString, []byte(`"d"`)},
+				{TokenObjectEnd, []byte("}")},
+				{TokenName, []byte(`""`)},
+				{TokenArrayStart, []byte("[")},
+				{TokenArrayEnd, []byte("]")},
+				{TokenObjectEnd, []byte("}")},
+			},
+		},
+		{
+			"[[[]], {}]",
+			[]Token{
+				{TokenArrayStart, []byte("[")},
+				{TokenArrayStart, []byte("[")},
+				{TokenArrayStart, []byte("[")},
+				{TokenArrayEnd, []byte("]")},
+				{TokenArrayEnd, []byte("]")},
+				{TokenObjectStart, []byte("{")},
+				{TokenObjectEnd, []byte("}")},
+				{TokenArrayEnd, []byte("]")},
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(
+			testCase.testJson,
+			func(t *testing.T) {
+				tokens, err := tokenize(Options{}, []byte(testCase.testJson))
+				require.Nil(t, err)
+				require.Equal(t, testCase.expectedTokens, tokens)
+			},
+		)
+	}
+	// Offset and Depth follow the tokens returned, and errors and io.EOF are returned again on every call.
+	testJson := []byte(` {"a": [1]} `)
+	tokenizer := NewTokenizer(testJson)
+	expectedOffsets := []int{2, 5, 8, 9, 10, 11}
+	expectedDepths := []int{1, 1, 2, 2, 1, 0}
+	for index := range expectedOffsets {
+		token, err := tokenizer.Next()
+		require.Nil(t, err)
+		require.Equal(t, expectedOffsets[index], tokenizer.Offset())
+		require.Equal(t, expectedDepths[index], tokenizer.Depth())
+		require.Equal(t, string(testJson[tokenizer.Offset()-len(token.Raw):tokenizer.Offset()]), string(token.Raw))
+	}
+	for range 2 {
+		token, err := tokenizer.Next()
+		require.Equal(t, io.EOF, err)
+		require.Equal(t, Token{}, token)
+	}
+	tokenizer = NewTokenizer([]byte("[1 2]"))
+	for range 2 {
+		_, err := tokenizer.Next()
+		require.Nil(t, err)
+	}
+	for range 2 {
+		_, err := tokenizer.Next()
+		require.NotNil(t, err)
+		require.Equal(t, "expected any of ,] at index 3 but read '2'", err.Error())
+	}
+	_, err := Options{MaxDepth: 2}.NewTokenizer([]byte("[[[]]]")).Next()
+	require.Nil(t, err)
+	_, err = tokenize(Options{MaxDepth: 2}, []byte("[[[]]]"))
+	require.Equal(t, "maximum depth exceeded at index 2", err.Error())
+	// Only the Tokenizer itself is allocated, however many tokens there are.
+	allocs := testing.AllocsPerRun(10, func() {
+		tokenizer := NewTokenizer(packageLockAxios)
+		for {
+			_, err = tokenizer.Next()
+			if err != nil {
+				break
+			}
+		}
+	})
+	require.Equal(t, io.EOF, err)
+	require.Equal(t, 1.0, allocs)
+}
+
+func TestTokenizerInvalidJsons(t *testing.T) {
+	for _, testCase := range invalidJsonTestCases {
+		t.Run(
+			testCase.testJson,
+			func(t *testing.T) {
+				_, err := tokenize(Options{}, []byte(testCase.testJson))
+				require.NotNil(t, err)
+				require.Equal(t, testCase.expectedError, err.Error())
+			},
+		)
+	}
+}
+
+// recordingVisitor records the events of Walk as strings, returning the error in results for the event, if any.
+type recordingVisitor struct {
+	events  []string
+	results map[string]error
+}
+
+func (visitor *recordingVisitor) record(event string) error {
+	visitor.events = append(visitor.events, event)
+	return visitor.results[event]
+}
+
+func (visitor *recordingVisitor) OnObjectStart() error {
+	return visitor.record("{")
+}
+
+func (visitor *recordingVisitor) OnObjectEnd() error {
+	return visitor.record("}")
+}
+
+func (visitor *recordingVisitor) OnName(raw []byte) error {
+	return visitor.record(string(raw) + ":")
+}
+
+func (visitor *recordingVisitor) OnArrayStart() error {
+	return visitor.record("[")
+}
+
+func (visitor *recordingVisitor) OnArrayEnd() error {
+	return visitor.record("]")
+}
+
+func (visitor *recordingVisitor) OnString(raw []byte) error {
+	return visitor.record(string(raw))
+}
+
+func (visitor *recordingVisitor) OnNumber(raw []byte) error {
+	return visitor.record(string(raw))
+}
+
+func (visitor *recordingVisitor) OnBool(value bool) error {
+	return visitor.record(strconv.FormatBool(value))
+}
+
+func (visitor *recordingVisitor) OnNull() error {
+	return visitor.record("null")
+}
+
+func TestWalk(t *testing.T) {
+	testJson := ` {"a": [1, "b\n", true, false, null], "c": {"d": -2.5e1, "e": []}, "f": {}} `
+	errCustom := errors.New("custom")
+	testCases := []struct {
+		name           string
+		results        map[string]error
+		expectedEvents []string
+		expectedError  error
+	}{
+		{
+			"All",
+			nil,
+			[]string{
+				"{", `"a":`, "[", "1", `"b\n"`, "true", "false", "null", "]",
+				`"c":`, "{", `"d":`, "-2.5e1", `"e":`, "[", "]", "}", `"f":`, "{", "}", "}",
+			},
+			nil,
+		},
+		{
+			"SkipArray",
+			map[string]error{"[": SkipSubtree},
+			[]string{"{", `"a":`, "[", `"c":`, "{", `"d":`, "-2.5e1", `"e":`, "[", "}", `"f":`, "{", "}", "}"},
+			nil,
+		},
+		{
+			"SkipMember",
+			map[string]error{`"c":`: SkipSubtree},
+			[]string{"{", `"a":`, "[", "1", `"b\n"`, "true", "false", "null", "]", `"c":`, `"f":`, "{", "}", "}"},
+			nil,
+		},
+		{
+			"SkipScalar",
+			map[string]error{"1": SkipSubtree, "}": SkipSubtree},
+			[]string{
+				"{", `"a":`, "[", "1", `"b\n"`, "true", "false", "null", "]",
+				`"c":`, "{", `"d":`, "-2.5e1", `"e":`, "[", "]", "}", `"f":`, "{", "}", "}",
+			},
+			nil,
+		},
+		{
+			"Stop",
+			map[string]error{"true": StopWalk},
+			[]string{"{", `"a":`, "[", "1", `"b\n"`, "true"},
+			nil,
+		},
+		{
+			"Error",
+			map[string]error{`"e":`: errCustom},
+			[]string{
+				"{", `"a":`, "[", "1", `"b\n"`, "true", "false", "null", "]", `"c":`, "{", `"d":`, "-2.5e1", `"e":`,
+			},
+			errCustom,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(
+			testCase.name,
+			func(t *testing.T) {
+				visitor := &recordingVisitor{results: testCase.results}
+				err := Walk([]byte(testJson), visitor)
+				require.Equal(t, testCase.expectedError, err)
+				require.Equal(t, testCase.expectedEvents, visitor.events)
+			},
+		)
+	}
+	// Events are received up to the point at which the JSON is found to be invalid, and skipped JSON is still
+	// validated.
+	visitor := &recordingVisitor{}
+	err := Walk([]byte(`[1, {"a": 2} 3]`), visitor)
+	require.NotNil(t, err)
+	require.Equal(t, "expected any of ,] at index 13 but read '3'", err.Error())
+	require.Equal(t, []string{"[", "1", "{", `"a":`, "2", "}"}, visitor.events)
+	visitor = &recordingVisitor{results: map[string]error{"[": SkipSubtree}}
+	err = Walk([]byte(`{"a": [1 2]}`), visitor)
+	require.NotNil(t, err)
+	require.Equal(t, "expected any of ,] at index 9 but read '2'", err.Error())
+	// Once the walk is stopped, nothing more is read.
+	visitor = &recordingVisitor{results: map[string]error{`"a":`: StopWalk}}
+	require.Nil(t, Walk([]byte(`{"a": [1 2]}`), visitor))
+	err = Options{MaxDepth: 2}.Walk([]byte("[[[]]]"), &recordingVisitor{})
+	require.NotNil(t, err)
+	require.Equal(t, "maximum depth exceeded at index 2", err.Error())
+}
+
+func TestWalkInvalidJsons(t *testing.T) {
+	for _, testCase := range invalidJsonTestCases {
+		t.Run(
+			testCase.testJson,
+			func(t *testing.T) {
+				err := Walk([]byte(testCase.testJson), &recordingVisitor{})
+				require.NotNil(t, err)
+				require.Equal(t, testCase.expectedError, err.Error())
+			},
+		)
+	}
+}
+
+func TestJsonLines(t *testing.T) {
+	testCases := []struct {
+		name                 string
+		jsonLines            string
+		continueOnError      bool
+		expectedErrors       []string
+		expectedRedactedJson string
+	}{
+		{"Empty", "", false, nil, ""},
+		{"Single", `{"a": "b"}`, false, nil, `{"a":""}`},
+		{"TrailingNewline", "1\n\"a\"\n", false, nil, "0\n\"\"\n"},
+		{"BlankLines", "\n[true]\n \t\r\n\r\n{}", false, nil, "\n[true]\n \t\r\n\r\n{}"},
+		{"CarriageReturns", "{\"a\": 1}\r\n[2] \r\n", false, nil, "{\"a\":0}\r\n[0]\r\n"},
+		{"Invalid", "1\n[\n{}\n2 3", false, []string{"line 2: read head ran out of json"}, ""},
+		{
+			"ContinueOnError",
+			"1\n[\n{\"a\": \"b\"}\n2 3\nnull",
+			true,
+			[]string{"line 2: read head ran out of json", "line 4: failed to consume entire json string"},
+			"0\n\n{\"a\":\"\"}\n\nnull",
+		},
+		{"ContinueOnErrorValid", "1\n2", true, nil, "0\n0"},
+		{
+			"SecondRecordOnSameLine",
+			`{"a": 1}{"a": 2}`,
+			false,
+			[]string{"line 1: failed to consume entire json string"},
+			"",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(
+			testCase.name,
+			func(t *testing.T) {
+				options := JsonLinesOptions{ContinueOnError: testCase.continueOnError}
+				checkErr := func(err error) {
+					if testCase.expectedErrors == nil {
+						require.Nil(t, err)
+						return
+					}
+					require.NotNil(t, err)
+					require.Equal(t, strings.Join(testCase.expectedErrors, "\n"), err.Error())
+					var lineError *LineError
+					require.ErrorAs(t, err, &lineError)
+					var syntaxError *SyntaxError
+					require.ErrorAs(t, err, &syntaxError)
+				}
+				checkErr(options.IsJsonLines([]byte(testCase.jsonLines)))
+				checkErr(options.ValidateJsonLinesReader(iotest.OneByteReader(strings.NewReader(testCase.jsonLines))))
+				redactedJson, err := options.RedactJsonLines([]byte(testCase.jsonLines))
+				checkErr(err)
+				require.Equal(t, testCase.expectedRedactedJson, string(redactedJson))
+				var writer bytes.Buffer
+				err = options.RedactJsonLinesReader(&writer, strings.NewReader(testCase.jsonLines))
+				checkErr(err)
+				if err == nil || testCase.continueOnError {
+					require.Equal(t, testCase.expectedRedactedJson, writer.String())
+				}
+			},
+		)
+	}
+	// The lines before an invalid record have already been written when it is found.
+	var writer bytes.Buffer
+	err := RedactJsonLinesReader(&writer, strings.NewReader("[1]\n\"a\"\n{\n2"))
+	require.NotNil(t, err)
+	require.Equal(t, "[0]\n\"\"\n", writer.String())
+	// Lines longer than the reader's buffer are read whole.
+	longRecord := `["` + strings.Repeat("a", 10000) + `"]`
+	require.Nil(t, ValidateJsonLinesReader(strings.NewReader(longRecord+"\n"+longRecord)))
+	err = ValidateJsonLinesReader(strings.NewReader(longRecord + "\n" + longRecord + "x"))
+	require.NotNil(t, err)
+	require.Equal(t, "line 2: failed to consume entire json string", err.Error())
+	// Errors from the reader are returned as they are.
+	err = JsonLinesOptions{ContinueOnError: true}.ValidateJsonLinesReader(
+		io.MultiReader(strings.NewReader("[\n"), iotest.ErrReader(errors.New("read failed"))),
+	)
+	require.NotNil(t, err)
+	require.Equal(t, "line 1: read head ran out of json\nread failed", err.Error())
+	err = JsonLinesOptions{Options: Options{MaxDepth: 1}}.IsJsonLines([]byte("[]\n[[]]"))
+	require.NotNil(t, err)
+	require.Equal(t, "line 2: maximum depth exceeded at index 1", err.Error())
+}
+
+func TestSplit(t *testing.T) {
+	testCases := []struct {
+		testJson       string
+		expectedValues []string
+		expectedError  string
+	}{
+		{"", nil, ""},
+		{" \n\t", nil, ""},
+		{"{}{}[1]", []string{"{}", "{}", "[1]"}, ""},
+		{` 1 2.5e3 "a""b" truefalse null[] `, []string{"1", "2.5e3", `"a"`, `"b"`, "true", "false", "null", "[]"}, ""},
+		{"{\"a\": [1, 2]}\n{\"b\": {}}\n", []string{`{"a": [1, 2]}`, `{"b": {}}`}, ""},
+		{"12", []string{"12"}, ""},
+		{"{}[1,]{}", []string{"{}"}, "expected any of \"10123456789{[tfn at index 5 but read ']'"},
+		{"[1] {", []string{"[1]"}, "read head ran out of json"},
+		{"[1] }", []string{"[1]"}, "expected any of \"10123456789{[tfn at index 4 but read '}'"},
 	}
 	for _, testCase := range testCases {
+		t.Run(
+			testCase.testJson,
+			func(t *testing.T) {
+				var values []string
+				var errs []error
+				for value, err := range Split([]byte(testCase.testJson)) {
+					if err != nil {
+						require.Nil(t, value)
+						errs = append(errs, err)
+					} else {
+						values = append(values, string(value))
+					}
+				}
+				require.Equal(t, testCase.expectedValues, values)
+				if testCase.expectedError == "" {
+					require.Len(t, errs, 0)
+				} else {
+					require.Len(t, errs, 1)
+					require.Equal(t, testCase.expectedError, errs[0].Error())
+				}
+			},
+		)
+	}
+	// Iteration can be stopped early, and the depth of each value is checked separately.
+	var values []string
+	for value, err := range (Options{MaxDepth: 1}).Split([]byte("[1][2][[3]]")) {
+		require.Nil(t, err)
+		values = append(values, string(value))
+		if len(values) == 2 {
+			break
+		}
+	}
+	require.Equal(t, []string{"[1]", "[2]"}, values)
+	for value, err := range (Options{MaxDepth: 1}).Split([]byte("[1][2][[3]]")) {
+		if err != nil {
+			require.Equal(t, "maximum depth exceeded at index 7", err.Error())
+		} else {
+			require.NotEqual(t, "[[3]]", string(value))
+		}
+	}
+}
+
+func TestDisallowDuplicateNames(t *testing.T) {
+	options := Options{DisallowDuplicateNames: true}
+	var manyNames []string
+	for index := range 40 {
+		manyNames = append(manyNames, fmt.Sprintf(`"n%d": {"a": %d, "n%d": [{"b": 1}, {"b": 2}]}`, index, index, index))
+	}
+	manyNamesJson := "{" + strings.Join(manyNames, ", ") + "}"
+	validTestCases := []string{
+		`{"a": 1, "b": 2}`,
+		`{"a": 1, "A": 2, "a ": 3, "ab": 4, "abc": 5}`,
+		`[{"a": 1}, {"a": 2}, {"a": {"a": {"a": 3}}}]`,
+		`{"a": {"b": 1}, "b": {"b": 1, "a": 1}, "c": [{"b": 1}, {"c": 1}]}`,
+		`{"é": 1, "e\u0301": 2, "😀": 3, "\ud83d\ude01": 4, "\u0000": 5, "\\u0000": 6}`,
+		manyNamesJson,
+	}
+	for _, testCase := range validTestCases {
 		t.Run(
 			testCase,
 			func(t *testing.T) {
-				err := IsJson([]byte(testCase))
+				require.Nil(t, options.IsJson([]byte(testCase)))
+				require.Nil(t, options.ValidateReader(iotest.OneByteReader(strings.NewReader(testCase))))
+				_, err := options.RedactAllValuesInPlace([]byte(testCase))
 				require.Nil(t, err)
 			},
 		)
 	}
+	invalidTestCases := []struct {
+		testJson      string
+		expectedError DuplicateNameError
+	}{
+		{`{"a": 1, "a": 2}`, DuplicateNameError{Name: "a", FirstOffset: 1, Offset: 9, Line: 1, Column: 10}},
+		{`{"a": 1, "\u0061": 2}`, DuplicateNameError{Name: "a", FirstOffset: 1, Offset: 9, Line: 1, Column: 10}},
+		{`{"é": 1, "\u00e9": 2}`, DuplicateNameError{Name: "é", FirstOffset: 1, Offset: 10, Line: 1, Column: 11}},
+		{
+			`{"\ud83d\ude00": 1, "😀": 2}`,
+			DuplicateNameError{Name: "😀", FirstOffset: 1, Offset: 20, Line: 1, Column: 21},
+		},
+		{
+			"{\"a\": {\"b\": 1, \"c\": 2},\n \"b\": {\"b\": 3, \"b\": 4}}",
+			DuplicateNameError{Name: "b", FirstOffset: 31, Offset: 39, Line: 2, Column: 16},
+		},
+		{
+			`[{"a": 1}, {"b": {}, "a": {}, "b": []}]`,
+			DuplicateNameError{Name: "b", FirstOffset: 12, Offset: 30, Line: 1, Column: 31},
+		},
+		{
+			manyNamesJson[:len(manyNamesJson)-1] + `, "n1": 2}`,
+			DuplicateNameError{
+				Name:        "n1",
+				FirstOffset: strings.Index(manyNamesJson, `"n1"`),
+				Offset:      len(manyNamesJson) + 1,
+				Line:        1,
+				Column:      len(manyNamesJson) + 2,
+			},
+		},
+		{
+			manyNamesJson[:len(manyNamesJson)-1] + `, "n39": 2}`,
+			DuplicateNameError{
+				Name:        "n39",
+				FirstOffset: strings.Index(manyNamesJson, `"n39"`),
+				Offset:      len(manyNamesJson) + 1,
+				Line:        1,
+				Column:      len(manyNamesJson) + 2,
+			},
+		},
+	}
+	for _, testCase := range invalidTestCases {
+		t.Run(
+			testCase.testJson,
+			func(t *testing.T) {
+				testJson := []byte(testCase.testJson)
+				var errs []error
+				errs = append(errs, options.IsJson(testJson))
+				errs = append(errs, options.ValidateReader(iotest.OneByteReader(bytes.NewReader(testJson))))
+				errs = append(errs, options.ValidateReader(bytes.NewReader(testJson)))
+				_, err := options.RedactAllValues(testJson)
+				errs = append(errs, err)
+				_, err = options.RedactAllValuesInPlace(bytes.Clone(testJson))
+				errs = append(errs, err)
+				_, err = options.CompactInPlace(bytes.Clone(testJson))
+				errs = append(errs, err)
+				_, err = IndentOptions{Options: options}.Indent(nil, testJson)
+				errs = append(errs, err)
+				_, err = tokenize(options, testJson)
+				errs = append(errs, err)
+				errs = append(errs, options.Walk(testJson, &recordingVisitor{}))
+				for _, err := range errs {
+					require.NotNil(t, err)
+					var duplicateNameError *DuplicateNameError
+					require.ErrorAs(t, err, &duplicateNameError)
+					require.Equal(t, testCase.expectedError, *duplicateNameError)
+				}
+				require.Nil(t, IsJson(testJson))
+			},
+		)
+	}
+	err := options.IsJson([]byte(`{"a\"b": 1, "a\u0022b": 2}`))
+	require.NotNil(t, err)
+	require.Equal(t, `duplicate name "a\"b" at index 12, first at index 1`, err.Error())
+	// Duplicates are found as soon as the second name is read, and syntax errors before it are found first.
+	err = options.IsJson([]byte(`{"a": 1, "a" 2}`))
+	require.Equal(t, `duplicate name "a" at index 9, first at index 1`, err.Error())
+	err = options.IsJson([]byte(`{"a": 1 "a": 2}`))
+	require.Equal(t, "expected any of ,} at index 8 but read '\"'", err.Error())
+	// The names of small objects are held inline, so the only allocation is of the state which holds them.
+	smallObjectsJson := []byte(validTestCases[3])
+	allocs := testing.AllocsPerRun(100, func() {
+		err = options.IsJson(smallObjectsJson)
+	})
+	require.Nil(t, err)
+	require.Equal(t, 1.0, allocs)
 }
 
-func TestRedactAllValues(t *testing.T) {
+func TestConforms(t *testing.T) {
+	template := ` {"id": 0, "name": "", "tags": [""], "owner": {"id": 0, "admin": true}, "extra": null, "any": []} `
 	testCases := []struct {
-		testJson     string
-		expectedJson string
+		testJson      string
+		expectedError *ConformanceError
 	}{
-		// Strings
-		{"\"\"", "\"\""},
-		{"\"a\"", "\"\""},
-		{"\"foo\"", "\"\""},
-		{"\"\\\"\"", "\"\""},
-		// Numbers
-		{"-3.14159E+123", "0"},
-		{"-3.14159e+123", "0"},
-		{"-3.14159", "0"},
-		{"-1", "0"},
-		{"-0.1", "0"},
-		{"-3.14159E-123", "0"},
-		{"-3.14159e-123", "0"},
-		{"-0", "0"},
-		{"0", "0"},
-		{"3.14159E-123", "0"},
-		{"3.14159e-123", "0"},
-		{"0.1", "0"},
-		{"1", "0"},
-		{"3.14159", "0"},
-		{"3.14159E+123", "0"},
-		{"3.14159e+123", "0"},
-		// Booleans & null
-		{"true", "true"},
-		{"false", "true"},
-		{"null", "null"},
-		// Various arrays
-		{"[]", "[]"},
-		{"[0]", "[0]"},
-		{"[0,1,2,3,4,5,6,7,8,9]", "[0,0,0,0,0,0,0,0,0,0]"},
-		// Various objects
-		{"{}", "{}"},
-		{"{\"\":\"\"}", "{\"\":\"\"}"},
-		{"{\"foo\":\"bar\"}", "{\"foo\":\"\"}"},
-		{"{\"foo\":0}", "{\"foo\":0}"},
-		{"{\"foo\":1}", "{\"foo\":0}"},
-		{"{\"foo\":0.1}", "{\"foo\":0}"},
-		{"{\"foo\":3.14159}", "{\"foo\":0}"},
-		{"{\"foo\":{}}", "{\"foo\":{}}"},
-		{"{\"foo\":{\"bar\":\"baz\"}}", "{\"foo\":{\"bar\":\"\"}}"},
-		{"{\"foo\":[]}", "{\"foo\":[]}"},
-		{"{\"foo\":[\"bar\"]}", "{\"foo\":[\"\"]}"},
-		{"{\"foo\": [\"bar\"]}", "{\"foo\":[\"\"]}"},
-		{"{\"foo\":true}", "{\"foo\":true}"},
-		{"{\"foo\":false}", "{\"foo\":true}"},
-		{"{\"foo\":null}", "{\"foo\":null}"},
-		// Whitespace in various positions
-		{" 0 ", "0"},
-		{"  0  ", "0"},
-		{"   0   ", "0"},
-		{" -0 ", "0"},
-		{" 0.1 ", "0"},
-		{" 1 ", "0"},
-		{" 3.14159 ", "0"},
-		{" 3.14159E+123 ", "0"},
-		{" true ", "true"},
-		{" false ", "true"},
-		{" null ", "null"},
-		{" [ ] ", "[]"},
-		{" { } ", "{}"},
-		{" { \"foo\" : \"bar\" } ", "{\"foo\":\"\"}"},
-		{" { \"foo\" : 0 } ", "{\"foo\":0}"},
-		{" { \"foo\" : 1 } ", "{\"foo\":0}"},
-		{" { \"foo\" : 0.1 } ", "{\"foo\":0}"},
-		{" { \"foo\" : 3.14159 } ", "{\"foo\":0}"},
-		{" { \"foo\" : { \"bar\" : \"baz\" } } ", "{\"foo\":{\"bar\":\"\"}}"},
-		{" { \"foo\" : [ \"bar\" ] } ", "{\"foo\":[\"\"]}"},
-		{" { \"foo\" : true } ", "{\"foo\":true}"},
-		{" { \"foo\" : false } ", "{\"foo\":true}"},
-		{" { \"foo\" : null } ", "{\"foo\":null}"},
+		{template, nil},
+		{`{"id":1,"name":"a","tags":[],"owner":{"id":2,"admin":false},"extra":null,"any":[1,"b",{}]}`, nil},
+		{
+			`{"any": [], "extra": null, "owner": {"admin": false, "id": -1.5e3}, ` +
+				`"tags": ["a", "b"], "name": "", "id": 1}`,
+			nil,
+		},
+		{`{"id":1,"n\u0061me":"a","tags":[],"owner":{"id":2,"admin":false},"extra":null,"any":[]}`, nil},
+		{
+			`{"id":"1","name":"a","tags":[],"owner":{"id":2,"admin":false},"extra":null,"any":[]}`,
+			&ConformanceError{Path: []string{"id"}, Offset: 6, Reason: "expected number but found string"},
+		},
+		{
+			`{"id":1,"name":"a","tags":["a",2],"owner":{"id":2,"admin":false},"extra":null,"any":[]}`,
+			&ConformanceError{Path: []string{"tags", "1"}, Offset: 31, Reason: "expected string but found number"},
+		},
+		{
+			`{"id":1,"name":"a","tags":[],"owner":{"id":2,"admin":null},"extra":null,"any":[]}`,
+			&ConformanceError{Path: []string{"owner", "admin"}, Offset: 53, Reason: "expected boolean but found null"},
+		},
+		{
+			`{"id":1,"name":"a","tags":[],"owner":{"id":2,"admin":true},"extra":{},"any":[]}`,
+			&ConformanceError{Path: []string{"extra"}, Offset: 67, Reason: "expected null but found object"},
+		},
+		{
+			`{"id":1,"name":"a","tags":[],"owner":{"id":2},"extra":null,"any":[]}`,
+			&ConformanceError{Path: []string{"owner"}, Offset: 37, Reason: `missing member "admin"`},
+		},
+		{
+			`{"id":1,"name":"a","tags":[],"owner":{"id":2,"admin":true,"email":""},"extra":null,"any":[]}`,
+			&ConformanceError{Path: []string{"owner"}, Offset: 58, Reason: `unexpected member "email"`},
+		},
+		{
+			`{"id":1,"name":"a","tags":[],"owner":{"id":2,"admin":true},"extra":null,"any":[],"any\n":[]}`,
+			&ConformanceError{Path: nil, Offset: 81, Reason: `unexpected member "any\n"`},
+		},
+		{`[]`, &ConformanceError{Path: nil, Offset: 0, Reason: "expected object but found array"}},
 	}
 	for _, testCase := range testCases {
 		t.Run(
 			testCase.testJson,
 			func(t *testing.T) {
-				redactedJson, err := RedactAllValues([]byte(testCase.testJson))
-				require.Nil(t, err)
-				require.Equal(t, testCase.expectedJson, string(redactedJson))
+				err := Conforms([]byte(testCase.testJson), []byte(template))
+				if testCase.expectedError == nil {
+					require.Nil(t, err)
+					return
+				}
+				var conformanceError *ConformanceError
+				require.ErrorAs(t, err, &conformanceError)
+				require.Equal(t, testCase.expectedError, conformanceError)
 			},
 		)
 	}
+	redacted, err := RedactAllValues([]byte(`[{"a": [[1.5, 2]], "b": {"c": "d"}}, {"a": [], "b": {"c": "e"}}]`))
+	require.Nil(t, err)
+	require.Nil(t, Conforms([]byte(` [ {"b": {"c": ""}, "a": [[], [3]]} ] `), redacted))
+	err = Conforms([]byte(`[{"b": {"c": ""}, "a": [[], ["3"]]}]`), redacted)
+	require.NotNil(t, err)
+	require.Equal(t, `expected number but found string at path ["0" "a" "1" "0"]`, err.Error())
+	// The members of the template object are matched afresh for each element which is checked against it.
+	err = Conforms([]byte(`[{"a": 1}, {"a": 2}, {}]`), []byte(`[{"a": 0}]`))
+	require.NotNil(t, err)
+	require.Equal(t, `missing member "a" at path ["2"]`, err.Error())
+	// The last member of the template with a repeated name is used.
+	require.Nil(t, Conforms([]byte(`{"a": 1}`), []byte(`{"a": 0, "a": 0}`)))
+	require.Nil(t, Conforms([]byte(`{"a": "b"}`), []byte(`{"a": 0, "\u0061": ""}`)))
+	err = Conforms([]byte(`{"a": 1}`), []byte(`{"a": 0, "a": ""}`))
+	require.NotNil(t, err)
+	require.Equal(t, `expected string but found number at path ["a"]`, err.Error())
+	err = Conforms([]byte(`{"a": 1,}`), []byte(`{"a": 0}`))
+	var syntaxError *SyntaxError
+	require.ErrorAs(t, err, &syntaxError)
+	require.Equal(t, "expected \" at index 8 but read '}'", err.Error())
+	err = Conforms([]byte(`{"a": 1}`), []byte(`{"a": 0`))
+	require.ErrorAs(t, err, &syntaxError)
+	require.Equal(t, "invalid template: read head ran out of json", err.Error())
+	err = Options{DisallowDuplicateNames: true}.Conforms([]byte(`{"a": 1, "a": 2}`), []byte(`{"a": 0}`))
+	var duplicateNameError *DuplicateNameError
+	require.ErrorAs(t, err, &duplicateNameError)
 }
 
-type invalidJsonTestCase struct {
-	testJson      string
-	expectedError string
+func TestInferSchema(t *testing.T) {
+	testCases := []struct {
+		samples        []string
+		expectedSchema string
+	}{
+		{[]string{`1`}, `{"type":"integer"}`},
+		{[]string{`1`, `-2.5`, `3`}, `{"type":"number"}`},
+		{[]string{`1e3`}, `{"type":"number"}`},
+		{[]string{`"a"`, `null`, `true`}, `{"type":["null","boolean","string"]}`},
+		{
+			[]string{`"2024-02-29T23:59:59Z"`, `"2024-01-02T03:04:05.123+01:00"`},
+			`{"type":"string","format":"date-time"}`,
+		},
+		{[]string{`"2024-01-02T03:04:05Z"`, `"2024-01-02"`}, `{"type":"string"}`},
+		{[]string{`"2024-01-02"`, `"2024-13-02"`}, `{"type":"string"}`},
+		{[]string{`"2024-01-02"`, `null`}, `{"type":["null","string"],"format":"date"}`},
+		{[]string{`"23:59:59.5Z"`, `"00:00:00-08:00"`}, `{"type":"string","format":"time"}`},
+		{[]string{`"123e4567-e89b-12d3-A456-426614174000"`}, `{"type":"string","format":"uuid"}`},
+		{[]string{`"123e4567-e89b-12d3-a456-42661417400g"`}, `{"type":"string"}`},
+		{[]string{`[]`, `[]`}, `{"type":"array"}`},
+		{[]string{`[]`, `[1, "a"]`, `[2.0]`}, `{"type":"array","items":{"type":["number","string"]}}`},
+		{[]string{`[[1], [[]]]`}, `{"type":"array","items":{"type":"array","items":{"type":["array","integer"]}}}`},
+		{[]string{`{}`}, `{"type":"object","properties":{},"required":[]}`},
+		{
+			[]string{`{"a": 1, "b": "x"}`, ` { "b" : null , "a" : 2 , "a" : 3 } `, `{"a": 4, "c\n": {}}`},
+			`{"type":"object","properties":{"a":{"type":"integer","x-frequency":1},"b":{"type":["null","string"],` +
+				`"x-frequency":0.667},"c\n":{"type":"object","properties":{},"required":[],"x-frequency":0.333}},` +
+				`"required":["a"]}`,
+		},
+		{
+			[]string{`[{"id": 1, "tags": ["a"]}, {"id": 2}]`},
+			`{"type":"array","items":{"type":"object","properties":{"id":{"type":"integer","x-frequency":1},` +
+				`"tags":{"type":"array","items":{"type":"string"},"x-frequency":0.5}},"required":["id"]}}`,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(
+			strings.Join(testCase.samples, " "),
+			func(t *testing.T) {
+				var samples [][]byte
+				for _, sample := range testCase.samples {
+					samples = append(samples, []byte(sample))
+				}
+				schema, err := InferSchema(samples...)
+				require.Nil(t, err)
+				expectedSchema := `{"$schema":"https://json-schema.org/draft/2020-12/schema",` +
+					testCase.expectedSchema[1:]
+				require.Equal(t, expectedSchema, string(schema))
+				require.Nil(t, IsJson(schema))
+			},
+		)
+	}
+	_, err := InferSchema()
+	require.NotNil(t, err)
+	require.Equal(t, "no samples to infer a schema from", err.Error())
+	_, err = InferSchema([]byte(`{}`), []byte(`{"a": 1,}`))
+	require.NotNil(t, err)
+	require.Equal(t, "invalid sample 1: expected \" at index 8 but read '}'", err.Error())
+	var syntaxError *SyntaxError
+	require.ErrorAs(t, err, &syntaxError)
+	_, err = Options{DisallowDuplicateNames: true}.InferSchema([]byte(`{"a": 1, "a": 2}`))
+	var duplicateNameError *DuplicateNameError
+	require.ErrorAs(t, err, &duplicateNameError)
 }
 
-var invalidJsonTestCases []invalidJsonTestCase = []invalidJsonTestCase{
-	{"", "jsonvalidator needs more than zero bytes"},
-	{" ", "read head ran out of json"},
-	{"j", "expected any of \"10123456789{[tfn at index 0 but read 'j'"},
-	{"\"", "read head ran out of json"},
-	{"\"\x1f", "expected any codepoint except \" or \\ or control characters at index 1 but read '\x1f'"},
-	{"\"\\z", "expected any of \"/\\bfnrtu at index 2 but read 'z'"},
-	{"\"\\u", "expected 4 hex digits but reached end of json"},
-	{"\"\\u0", "expected 4 hex digits but reached end of json"},
-	{"\"\\u00", "expected 4 hex digits but reached end of json"},
-	{"\"\\u000", "expected 4 hex digits but reached end of json"},
-	{"\"\\u0000", "expected \" but reached end of json"},
-	{"\"\\u000z", "expected 4 hex digits at index 6 but read 'z'"},
-	{"\"\\u00000", "expected \" but reached end of json"},
-	{"\"\\u0000z", "expected \" but reached end of json"},
-	{"{", "read head ran out of json"},
-	{"{\"foo\":\"bar\"", "read head ran out of json"},
-	{"{\"foo\":\"bar\",", "read head ran out of json"},
-	{"{\"foo", "expected \" but reached end of json"},
-	{"{\"foo\" ", "read head ran out of json"},
-	{"{\"foo\";", "expected : at index 6 but read ';'"},
-	{"{\"foo\":", "read head ran out of json"},
-	{"[", "read head ran out of json"},
-	{"[,", "read head ran out of json"},
-	{"[\"", "read head ran out of json"},
-	{"\"f", "expected \" but reached end of json"},
-	{"-", "expected any of 0123456789 but reached end of json"},
-	{"0.", "expected any of 0123456789 but reached end of json"},
-	{"0E", "expected + or - but reached end of json"},
-	{"0E-", "read head ran out of json"},
-	{"foo", "expected a at index 1 but read 'o'"},
-	{"f", "expected a but reached end of json"},
-	{"t", "expected r but reached end of json"},
-	{"n", "expected u but reached end of json"},
-	{"{\"foo\":\"bar\"} {\"foo\":\"bar\"}", "failed to consume entire json string"},
+func TestStats(t *testing.T) {
+	testCases := []struct {
+		testJson      string
+		expectedStats DocumentStats
+	}{
+		{`1`, DocumentStats{Numbers: 1, Bytes: 1, ValueBytes: 1}},
+		{"\t\"ab\" \n", DocumentStats{Strings: 1, LongestString: 2, Bytes: 7, ValueBytes: 4, WhitespaceBytes: 3}},
+		{`{}`, DocumentStats{Objects: 1, MaxDepth: 1, Bytes: 2}},
+		{`[[[]],[]]`, DocumentStats{Arrays: 4, MaxDepth: 3, Bytes: 9}},
+		{
+			` {"a": [1, "xy", true, null], "bc": {"d": "a\"b"}, "e": []} `,
+			DocumentStats{
+				Objects:         2,
+				Arrays:          2,
+				Strings:         2,
+				Numbers:         1,
+				Booleans:        1,
+				Nulls:           1,
+				MaxDepth:        2,
+				LongestString:   4,
+				Bytes:           60,
+				KeyBytes:        13,
+				ValueBytes:      19,
+				WhitespaceBytes: 11,
+			},
+		},
+		{
+			`{"a_long_name": "", "b": false}`,
+			DocumentStats{
+				Objects:         1,
+				Strings:         1,
+				Booleans:        1,
+				MaxDepth:        1,
+				Bytes:           31,
+				KeyBytes:        16,
+				ValueBytes:      7,
+				WhitespaceBytes: 3,
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(
+			testCase.testJson,
+			func(t *testing.T) {
+				stats, err := Stats([]byte(testCase.testJson))
+				require.Nil(t, err)
+				require.Equal(t, testCase.expectedStats, stats)
+			},
+		)
+	}
+	stats, err := Stats([]byte("[1,\n 2]\n"))
+	require.Nil(t, err)
+	require.Equal(t, 0.375, stats.WhitespaceRatio())
+	require.Equal(t, 0.0, DocumentStats{}.WhitespaceRatio())
+	var allocs float64
+	allocs = testing.AllocsPerRun(10, func() {
+		stats, err = Stats(packageLockAxios)
+	})
+	require.Nil(t, err)
+	require.Equal(t, 0.0, allocs)
+	require.Equal(t, len(packageLockAxios), stats.Bytes)
+	require.Greater(t, stats.Objects, 0)
+	require.Greater(t, stats.WhitespaceRatio(), 0.0)
+	_, err = Options{MaxDepth: 2}.Stats([]byte(`[[[]]]`))
+	require.NotNil(t, err)
+	require.Equal(t, "maximum depth exceeded at index 2", err.Error())
+	_, err = Options{DisallowDuplicateNames: true}.Stats([]byte(`{"a": 1, "a": 2}`))
+	var duplicateNameError *DuplicateNameError
+	require.ErrorAs(t, err, &duplicateNameError)
 }
 
-func TestIsJsonInvalidJsons(t *testing.T) {
+func TestStatsInvalidJsons(t *testing.T) {
 	for _, testCase := range invalidJsonTestCases {
 		t.Run(
 			testCase.testJson,
 			func(t *testing.T) {
-				err := IsJson([]byte(testCase.testJson))
+				_, err := Stats([]byte(testCase.testJson))
 				require.NotNil(t, err)
 				require.Equal(t, testCase.expectedError, err.Error())
 			},
@@ -238,14 +2083,44 @@ func TestIsJsonInvalidJsons(t *testing.T) {
 	}
 }
 
-func TestRedactAllValuesInvalidJsons(t *testing.T) {
-	for _, testCase := range invalidJsonTestCases {
+func TestSyntaxError(t *testing.T) {
+	testCases := []struct {
+		testJson      string
+		expectedError SyntaxError
+	}{
+		{"", SyntaxError{Kind: SyntaxErrorEmptyInput, Offset: 0, Line: 1, Column: 1}},
+		{"j", SyntaxError{
+			Kind: SyntaxErrorUnexpectedCharacter, Offset: 0, Line: 1, Column: 1, Found: 'j',
+			Expected: "any of \"10123456789{[tfn",
+		}},
+		{"{\n  \"foo\": tru\n}", SyntaxError{
+			Kind: SyntaxErrorUnexpectedCharacter, Offset: 14, Line: 2, Column: 13, Found: '\n', Expected: "e",
+		}},
+		{"[\n\t\"foo\",\n\t\"bar\x01\"\n]", SyntaxError{
+			Kind: SyntaxErrorUnexpectedCharacter, Offset: 15, Line: 3, Column: 6, Found: '\x01',
+			Expected: "any codepoint except \" or \\ or control characters",
+		}},
+		{"{\n  \"foo\": \"bar\"\n", SyntaxError{Kind: SyntaxErrorUnexpectedEnd, Offset: 17, Line: 3, Column: 1}},
+		{"\"f", SyntaxError{Kind: SyntaxErrorUnexpectedEnd, Offset: 2, Line: 1, Column: 3, Expected: "\""}},
+		{"{}\n\n{}", SyntaxError{Kind: SyntaxErrorTrailingData, Offset: 4, Line: 3, Column: 1, Found: '{'}},
+	}
+	for _, testCase := range testCases {
 		t.Run(
 			testCase.testJson,
 			func(t *testing.T) {
-				_, err := RedactAllValues([]byte(testCase.testJson))
-				require.NotNil(t, err)
-				require.Equal(t, testCase.expectedError, err.Error())
+				var syntaxError *SyntaxError
+				err := IsJson([]byte(testCase.testJson))
+				require.True(t, errors.As(err, &syntaxError))
+				require.Equal(t, testCase.expectedError, *syntaxError)
+				_, err = RedactAllValues([]byte(testCase.testJson))
+				require.True(t, errors.As(err, &syntaxError))
+				require.Equal(t, testCase.expectedError, *syntaxError)
+				_, err = RedactAllValuesInPlace([]byte(testCase.testJson))
+				require.True(t, errors.As(err, &syntaxError))
+				require.Equal(t, testCase.expectedError, *syntaxError)
+				err = ValidateReader(iotest.OneByteReader(strings.NewReader(testCase.testJson)))
+				require.True(t, errors.As(err, &syntaxError))
+				require.Equal(t, testCase.expectedError, *syntaxError)
 			},
 		)
 	}
@@ -381,6 +2256,9 @@ func BenchmarkIsJson(b *testing.B) {
 		implementation func(json []byte) error
 	}{
 		{"JsonBytes", IsJson},
+		{"JsonBytesReader", func(maybeJson []byte) error {
+			return ValidateReader(bytes.NewReader(maybeJson))
+		}},
 		{"EncodingJson", func(maybeJson []byte) error {
 			var unmashalled interface{}
 			return json.Unmarshal(maybeJson, &unmashalled)
@@ -423,6 +2301,7 @@ func BenchmarkRedactAllValues(b *testing.B) {
 		implementation func(json []byte) ([]byte, error)
 	}{
 		{"JsonBytes", RedactAllValues},
+		{"JsonBytesInPlace", RedactAllValuesInPlace},
 		{"EncodingJson", func(v []byte) ([]byte, error) {
 			var unmashalled interface{}
 			err := json.Unmarshal(v, &unmashalled)
@@ -461,6 +2340,166 @@ func BenchmarkRedactAllValues(b *testing.B) {
 	}
 }
 
+func BenchmarkCompact(b *testing.B) {
+	implementations := []struct {
+		name           string
+		implementation func(json []byte) ([]byte, error)
+	}{
+		{"JsonBytes", func(v []byte) ([]byte, error) {
+			return Compact(make([]byte, 0, len(v)), v)
+		}},
+		{"JsonBytesInPlace", CompactInPlace},
+		{"EncodingJson", func(v []byte) ([]byte, error) {
+			compacted := bytes.NewBuffer(make([]byte, 0, len(v)))
+			err := json.Compact(compacted, v)
+			return compacted.Bytes(), err
+		}},
+	}
+	for _, testCase := range testJsonCases {
+		for _, implementation := range implementations {
+			b.Run(
+				testCase.name+"/"+implementation.name,
+				func(b *testing.B) {
+					testJsonLen := len(*testCase.testJson)
+					b.ResetTimer()
+					b.StopTimer()
+					for n := 0; n < b.N; n++ {
+						testJsonCopy := make([]byte, testJsonLen)
+						copy(testJsonCopy, *testCase.testJson)
+						b.StartTimer()
+						_, err := implementation.implementation(testJsonCopy)
+						b.StopTimer()
+						if err != nil {
+							log.Println(err.Error())
+							b.FailNow()
+						}
+					}
+				},
+			)
+		}
+	}
+}
+
+func BenchmarkGet(b *testing.B) {
+	path := []string{"packages", "node_modules/yargs", "dependencies", "y18n"}
+	b.Run(
+		"PackageLockAxios/JsonBytes",
+		func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				_, _, err := Get(packageLockAxios, path...)
+				if err != nil {
+					log.Println(err.Error())
+					b.FailNow()
+				}
+			}
+		},
+	)
+	b.Run(
+		"PackageLockAxios/EncodingJson",
+		func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				var unmarshalled map[string]interface{}
+				err := json.Unmarshal(packageLockAxios, &unmarshalled)
+				if err != nil {
+					log.Println(err.Error())
+					b.FailNow()
+				}
+				var value interface{} = unmarshalled
+				for _, element := range path {
+					value = value.(map[string]interface{})[element]
+				}
+			}
+		},
+	)
+}
+
+func BenchmarkGetMany(b *testing.B) {
+	var paths [][]string
+	for _, dependency := range []string{"y18n", "cliui", "escalade", "string-width"} {
+		paths = append(paths, []string{"packages", "node_modules/yargs", "dependencies", dependency})
+	}
+	b.Run(
+		"PackageLockAxios/JsonBytesGetMany",
+		func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				_, err := GetMany(packageLockAxios, paths)
+				if err != nil {
+					log.Println(err.Error())
+					b.FailNow()
+				}
+			}
+		},
+	)
+	b.Run(
+		"PackageLockAxios/JsonBytesGet",
+		func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				for _, path := range paths {
+					_, _, err := Get(packageLockAxios, path...)
+					if err != nil {
+						log.Println(err.Error())
+						b.FailNow()
+					}
+				}
+			}
+		},
+	)
+}
+
+func BenchmarkTokenizer(b *testing.B) {
+	b.Run(
+		"PackageLockAxios/JsonBytes",
+		func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				tokenizer := NewTokenizer(packageLockAxios)
+				for {
+					_, err := tokenizer.Next()
+					if err == io.EOF {
+						break
+					}
+					if err != nil {
+						log.Println(err.Error())
+						b.FailNow()
+					}
+				}
+			}
+		},
+	)
+	b.Run(
+		"PackageLockAxios/EncodingJson",
+		func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				decoder := json.NewDecoder(bytes.NewReader(packageLockAxios))
+				for {
+					_, err := decoder.Token()
+					if err == io.EOF {
+						break
+					}
+					if err != nil {
+						log.Println(err.Error())
+						b.FailNow()
+					}
+				}
+			}
+		},
+	)
+}
+
+func BenchmarkStats(b *testing.B) {
+	b.Run(
+		"PackageLockAxios/JsonBytes",
+		func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				_, err := Stats(packageLockAxios)
+				if err != nil {
+					log.Println(err.Error())
+					b.FailNow()
+				}
+			}
+		},
+	)
+}
+
 func BenchmarkRedactAllValuesPackageLockAxiosEncodingJsonPremarshalled(b *testing.B) {
 	b.ResetTimer()
 	b.StopTimer()
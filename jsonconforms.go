@@ -0,0 +1,198 @@
+package jsonbytes
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// templateMember is a member of an object in a template, which is matched with the member of the same name in the
+// document.
+type templateMember struct {
+	// name is the bytes of the name between its quotes, and value is the span of the value, excluding whitespace.
+	name  []byte
+	value []byte
+	// matchedIn is the number of the object in the document the member was last matched in, or zero if none.
+	matchedIn int
+}
+
+// conformer checks that a document has the same shape as a template as it consumes the document. Both the document
+// and the template must already have been validated, so the errors returned when consuming them are ignored, and
+// options need only have the same MaxDepth as they were validated with.
+type conformer struct {
+	options Options
+	// document is positioned at the start of the value being checked, and path is the path to it.
+	document *jsonValidator
+	path     []string
+	// templateObjects and templateArrays hold the members and first element of the objects and arrays in the template
+	// which have been checked against, keyed by the address of their first byte, so that each is only parsed once
+	// however many values in the document are checked against it.
+	templateObjects map[*byte][]templateMember
+	templateArrays  map[*byte][]byte
+	// objects is the number of objects in the document which have been checked so far.
+	objects int
+}
+
+func (conformer *conformer) errorAt(offset int, format string, args ...any) error {
+	reason := fmt.Sprintf(format, args...)
+	return &ConformanceError{Path: append([]string(nil), conformer.path...), Offset: offset, Reason: reason}
+}
+
+// conformValue consumes the value at the start of the document, checking that it has the same shape as template, the
+// span of a value in the template.
+func (conformer *conformer) conformValue(template []byte) error {
+	document := conformer.document
+	valueStart := document.readIndex
+	found, expected := kindOf(document.readHead), kindOf(template[0])
+	if found != expected {
+		return conformer.errorAt(valueStart, "expected %s but found %s", expected, found)
+	}
+	switch found {
+	case KindObject:
+		return conformer.conformObject(template)
+	case KindArray:
+		return conformer.conformArray(template)
+	}
+	document.consumeValue()
+	return nil
+}
+
+func (conformer *conformer) conformObject(template []byte) error {
+	document := conformer.document
+	objectStart := document.readIndex
+	members := conformer.templateMembers(template)
+	conformer.objects += 1
+	object := conformer.objects
+	document.readUnsafe()
+	document.consumeWhitespace()
+	for memberIndex := 0; document.readHead != '}'; memberIndex++ {
+		nameStart := document.readIndex
+		document.consumeName()
+		name := document.json[nameStart+1 : document.readIndex-1]
+		member := findTemplateMember(members, memberIndex, name)
+		if member == nil {
+			return conformer.errorAt(nameStart, "unexpected member %q", appendUnescaped(nil, name))
+		}
+		member.matchedIn = object
+		document.consumeWhitespace()
+		document.readUnsafe()
+		document.consumeWhitespace()
+		conformer.path = append(conformer.path, string(appendUnescaped(nil, name)))
+		err := conformer.conformValue(member.value)
+		if err != nil {
+			return err
+		}
+		conformer.path = conformer.path[:len(conformer.path)-1]
+		if document.readHead == ',' {
+			document.readUnsafe()
+			document.consumeWhitespace()
+		}
+	}
+	for _, member := range members {
+		if member.matchedIn != object {
+			return conformer.errorAt(objectStart, "missing member %q", appendUnescaped(nil, member.name))
+		}
+	}
+	document.readUnsafe()
+	document.consumeWhitespace()
+	return nil
+}
+
+func (conformer *conformer) conformArray(template []byte) error {
+	document := conformer.document
+	elementTemplate := conformer.firstTemplateElement(template)
+	document.readUnsafe()
+	document.consumeWhitespace()
+	for index := 0; document.readHead != ']'; index++ {
+		if elementTemplate == nil {
+			document.consumeValue()
+		} else {
+			conformer.path = append(conformer.path, strconv.Itoa(index))
+			err := conformer.conformValue(elementTemplate)
+			if err != nil {
+				return err
+			}
+			conformer.path = conformer.path[:len(conformer.path)-1]
+		}
+		if document.readHead == ',' {
+			document.readUnsafe()
+			document.consumeWhitespace()
+		}
+	}
+	document.readUnsafe()
+	document.consumeWhitespace()
+	return nil
+}
+
+// templateMembers returns the members of template, the span of an object in the template. If a name appears more than
+// once, only the last member with it is returned, as that is the one encoding/json would decode.
+func (conformer *conformer) templateMembers(template []byte) []templateMember {
+	members, found := conformer.templateObjects[&template[0]]
+	if found {
+		return members
+	}
+	state, _ := newJsonValidator(template, conformer.options)
+	state.readUnsafe()
+	state.consumeWhitespace()
+	for state.readHead != '}' {
+		nameStart := state.readIndex
+		state.consumeName()
+		name := template[nameStart+1 : state.readIndex-1]
+		state.consumeWhitespace()
+		state.readUnsafe()
+		state.consumeWhitespace()
+		valueStart := state.readIndex
+		state.consumeValue()
+		value := template[valueStart:state.valueEnd()]
+		if member := findTemplateMember(members, len(members), name); member != nil {
+			member.value = value
+		} else {
+			members = append(members, templateMember{name: name, value: value})
+		}
+		if state.readHead == ',' {
+			state.readUnsafe()
+			state.consumeWhitespace()
+		}
+	}
+	if conformer.templateObjects == nil {
+		conformer.templateObjects = make(map[*byte][]templateMember)
+	}
+	conformer.templateObjects[&template[0]] = members
+	return members
+}
+
+// firstTemplateElement returns the span of the first element of template, the span of an array in the template, or
+// nil if it is empty.
+func (conformer *conformer) firstTemplateElement(template []byte) []byte {
+	element, found := conformer.templateArrays[&template[0]]
+	if found {
+		return element
+	}
+	state, _ := newJsonValidator(template, conformer.options)
+	state.readUnsafe()
+	state.consumeWhitespace()
+	if state.readHead != ']' {
+		elementStart := state.readIndex
+		state.consumeValue()
+		element = template[elementStart:state.valueEnd()]
+	}
+	if conformer.templateArrays == nil {
+		conformer.templateArrays = make(map[*byte][]byte)
+	}
+	conformer.templateArrays[&template[0]] = element
+	return element
+}
+
+// findTemplateMember returns the member of members called name, the bytes of a name between its quotes, or nil if
+// there is none. Objects in documents usually have their members in the same order as in the template, so the member
+// at memberIndex is tried first.
+func findTemplateMember(members []templateMember, memberIndex int, name []byte) *templateMember {
+	if memberIndex < len(members) && equalRawNames(members[memberIndex].name, name) {
+		return &members[memberIndex]
+	}
+	for index := range members {
+		if equalRawNames(members[index].name, name) {
+			return &members[index]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package jsonbytes
+
+// editLocation describes where the value at a path was found in JSON, or where it would be inserted if it was not.
+type editLocation struct {
+	found bool
+	// valueStart and valueEnd are the span of the value, excluding whitespace, and memberStart is the start of its name
+	// if it is the value of a member of an object, else the same as valueStart.
+	memberStart int
+	valueStart  int
+	valueEnd    int
+	// previousEnd is the end of the value of the member or element before it, and nextStart is the start of the member
+	// or element after it, or -1 if there is none.
+	previousEnd int
+	nextStart   int
+	// If the value was not found, but the container it would be in is an object, inObject is set, and previousEnd is
+	// the end of the value of its last member, or -1 if it is empty, in which case closeIndex is the index of its
+	// closing byte.
+	inObject   bool
+	closeIndex int
+}
+
+// locateForEdit finds the value at path in JSON in the same manner as consumeToPath. ErrPathNotFound is returned if
+// there is no value at path and it cannot be inserted either, because the container it would be in does not exist. The
+// JSON must already have been validated with the same options, so the errors returned when consuming it are ignored.
+func (state *jsonValidator) locateForEdit(path []string) (editLocation, error) {
+	state.consumeWhitespace()
+	location := editLocation{memberStart: state.readIndex, valueStart: state.readIndex, previousEnd: -1, nextStart: -1}
+	for elementIndex, element := range path {
+		location = editLocation{previousEnd: -1, nextStart: -1}
+		var found bool
+		switch state.readHead {
+		case '{':
+			found = state.locateMember(element, &location)
+		case '[':
+			found = state.locateElement(element, &location)
+		default:
+			return editLocation{}, ErrPathNotFound
+		}
+		if !found {
+			if elementIndex != len(path)-1 || !location.inObject {
+				return editLocation{}, ErrPathNotFound
+			}
+			return location, nil
+		}
+	}
+	location.found = true
+	state.consumeValue()
+	location.valueEnd = state.valueEnd()
+	if len(path) != 0 && state.readHead == ',' {
+		state.readUnsafe()
+		state.consumeWhitespace()
+		location.nextStart = state.readIndex
+	}
+	return location, nil
+}
+
+// locateMember consumes an object up to the start of the value of the first member called name, recording where it is
+// in location, or else records where a member called name would be inserted.
+func (state *jsonValidator) locateMember(name string, location *editLocation) bool {
+	state.readUnsafe()
+	state.consumeWhitespace()
+	location.inObject = true
+	for state.readHead != '}' {
+		location.memberStart = state.readIndex
+		state.consumeName()
+		found := equalUnescaped(state.json[location.memberStart+1:state.readIndex-1], name)
+		state.consumeWhitespace()
+		state.readUnsafe()
+		state.consumeWhitespace()
+		if found {
+			location.valueStart = state.readIndex
+			return true
+		}
+		state.consumeValue()
+		location.previousEnd = state.valueEnd()
+		if state.readHead == ',' {
+			state.readUnsafe()
+			state.consumeWhitespace()
+		}
+	}
+	location.closeIndex = state.readIndex
+	return false
+}
+
+// locateElement consumes an array up to the start of the element at the decimal index element, recording where it is
+// in location.
+func (state *jsonValidator) locateElement(element string, location *editLocation) bool {
+	state.readUnsafe()
+	state.consumeWhitespace()
+	target, isIndex := parseArrayIndex(element)
+	for index := 0; state.readHead != ']'; index++ {
+		if isIndex && index == target {
+			location.memberStart = state.readIndex
+			location.valueStart = state.readIndex
+			return true
+		}
+		state.consumeValue()
+		location.previousEnd = state.valueEnd()
+		if state.readHead == ',' {
+			state.readUnsafe()
+			state.consumeWhitespace()
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package jsonbytes
+
+import "fmt"
+
+// SyntaxErrorKind is a machine-readable classification of why a SyntaxError was returned.
+type SyntaxErrorKind int
+
+const (
+	// SyntaxErrorEmptyInput indicates that the input contained zero bytes.
+	SyntaxErrorEmptyInput SyntaxErrorKind = iota
+	// SyntaxErrorUnexpectedCharacter indicates that a byte was read which cannot appear at that position.
+	SyntaxErrorUnexpectedCharacter
+	// SyntaxErrorUnexpectedEnd indicates that the input ended before the JSON value was complete.
+	SyntaxErrorUnexpectedEnd
+	// SyntaxErrorTrailingData indicates that a complete JSON value was followed by more non-whitespace bytes.
+	SyntaxErrorTrailingData
+	// SyntaxErrorInvalidUTF8 indicates that a string or name contained a byte which is not valid UTF-8 at that
+	// position. It is only returned when Options.ValidateUTF8 is set.
+	SyntaxErrorInvalidUTF8
+	// SyntaxErrorUnpairedSurrogate indicates that a string or name contained a \u escape of a UTF-16 surrogate which
+	// was not part of a surrogate pair. It is only returned when Options.ValidateUTF8 is set.
+	SyntaxErrorUnpairedSurrogate
+	// SyntaxErrorMaxDepthExceeded indicates that objects and arrays were nested deeper than Options.MaxDepth permits.
+	SyntaxErrorMaxDepthExceeded
+)
+
+func (kind SyntaxErrorKind) String() string {
+	switch kind {
+	case SyntaxErrorEmptyInput:
+		return "empty input"
+	case SyntaxErrorUnexpectedCharacter:
+		return "unexpected character"
+	case SyntaxErrorUnexpectedEnd:
+		return "unexpected end"
+	case SyntaxErrorTrailingData:
+		return "trailing data"
+	case SyntaxErrorInvalidUTF8:
+		return "invalid UTF-8"
+	case SyntaxErrorUnpairedSurrogate:
+		return "unpaired surrogate"
+	case SyntaxErrorMaxDepthExceeded:
+		return "max depth exceeded"
+	}
+	return fmt.Sprintf("SyntaxErrorKind(%d)", int(kind))
+}
+
+// SyntaxError describes where and why a []byte was found not to be a valid JSON value. It is returned by IsJson and
+// RedactAllValues, so callers can use errors.As to retrieve it. Offset is the zero-based index of the byte at which
+// the error was detected, and Line and Column are the one-based position of that byte, where Column counts bytes
+// since the last '\n'; for an unpaired surrogate, this is the backslash which begins its \u escape. Found is the byte
+// that was read at Offset, and is zero when the error was caused by reaching the end of the input. Expected describes
+// the bytes that would have been accepted at Offset, and may be empty.
+type SyntaxError struct {
+	Kind     SyntaxErrorKind
+	Offset   int
+	Line     int
+	Column   int
+	Found    byte
+	Expected string
+}
+
+func (err *SyntaxError) Error() string {
+	switch err.Kind {
+	case SyntaxErrorEmptyInput:
+		return "jsonvalidator needs more than zero bytes"
+	case SyntaxErrorUnexpectedEnd:
+		if err.Expected == "" {
+			return "read head ran out of json"
+		}
+		return fmt.Sprintf("expected %s but reached end of json", err.Expected)
+	case SyntaxErrorTrailingData:
+		return "failed to consume entire json string"
+	case SyntaxErrorInvalidUTF8:
+		return fmt.Sprintf("invalid UTF-8 byte 0x%02x at index %d", err.Found, err.Offset)
+	case SyntaxErrorUnpairedSurrogate:
+		return fmt.Sprintf("unpaired UTF-16 surrogate escape at index %d", err.Offset)
+	case SyntaxErrorMaxDepthExceeded:
+		return fmt.Sprintf("maximum depth exceeded at index %d", err.Offset)
+	}
+	return fmt.Sprintf("expected %s at index %d but read '%s'", err.Expected, err.Offset, string(err.Found))
+}
+
+// LineError describes a record of JSON Lines which is not a valid JSON value. Line is the one-based number of the line
+// the record is on, and Err is the error for the record on its own, which is always a *SyntaxError, unless it is a
+// *DuplicateNameError.
+type LineError struct {
+	Line int
+	Err  error
+}
+
+func (err *LineError) Error() string {
+	return fmt.Sprintf("line %d: %s", err.Line, err.Err.Error())
+}
+
+func (err *LineError) Unwrap() error {
+	return err.Err
+}
+
+// DuplicateNameError describes an object which has more than one member with the same name, which is only an error
+// when Options.DisallowDuplicateNames is set. Name is the name once its escape sequences have been decoded, and
+// FirstOffset and Offset are the zero-based indexes of the opening quotes of the first and second members with the
+// name. Line and Column are the position of the second member, in the same manner as for a SyntaxError.
+type DuplicateNameError struct {
+	Name        string
+	FirstOffset int
+	Offset      int
+	Line        int
+	Column      int
+}
+
+func (err *DuplicateNameError) Error() string {
+	return fmt.Sprintf("duplicate name %q at index %d, first at index %d", err.Name, err.Offset, err.FirstOffset)
+}
+
+// ConformanceError describes how a document does not have the same shape as the template it was checked against by
+// Conforms. Path is the path of the value at fault, in the same form as the path passed to Get, or of the object with
+// a member which is missing or should not be there. Offset is the zero-based index in the document of the value at
+// fault, the name of the member which should not be there, or the object a member is missing from.
+type ConformanceError struct {
+	Path   []string
+	Offset int
+	Reason string
+}
+
+func (err *ConformanceError) Error() string {
+	return fmt.Sprintf("%s at path %q", err.Reason, err.Path)
+}
@@ -0,0 +1,409 @@
+package jsonbytes
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrPathNotFound is returned by Get when the JSON is valid as far as it was read, but there is no value at the path.
+var ErrPathNotFound = errors.New("path not found")
+
+// Kind is the type of a JSON value.
+type Kind int
+
+const (
+	// KindInvalid is returned along with an error, when there is no value.
+	KindInvalid Kind = iota
+	KindString
+	KindNumber
+	KindBoolean
+	KindNull
+	KindObject
+	KindArray
+)
+
+func (kind Kind) String() string {
+	switch kind {
+	case KindInvalid:
+		return "invalid"
+	case KindString:
+		return "string"
+	case KindNumber:
+		return "number"
+	case KindBoolean:
+		return "boolean"
+	case KindNull:
+		return "null"
+	case KindObject:
+		return "object"
+	case KindArray:
+		return "array"
+	}
+	return fmt.Sprintf("Kind(%d)", int(kind))
+}
+
+// kindOf returns the Kind of the valid JSON value which begins with firstByte.
+func kindOf(firstByte byte) Kind {
+	switch firstByte {
+	case '"':
+		return KindString
+	case 't', 'f':
+		return KindBoolean
+	case 'n':
+		return KindNull
+	case '{':
+		return KindObject
+	case '[':
+		return KindArray
+	}
+	return KindNumber
+}
+
+// consumeToPath consumes JSON up to the start of the value at path, which is returned without being consumed, each
+// element of path being the name of a member of an object or the decimal index of an element of an array. Members and
+// elements which are not on the path are consumed without being looked at further, and the JSON following the value
+// is not consumed at all. If a name appears more than once in an object, the first member with that name is used.
+func (state *jsonValidator) consumeToPath(path []string) (int, error) {
+	state.consumeWhitespace()
+	for _, element := range path {
+		if state.readIndex == state.jsonLength {
+			return 0, state.errorUnexpectedEnd()
+		}
+		var err error
+		switch state.readHead {
+		case '{':
+			err = state.consumeToMember(element)
+		case '[':
+			err = state.consumeToElement(element)
+		default:
+			err = state.consumeValue()
+			if err == nil {
+				err = ErrPathNotFound
+			}
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	if state.readIndex == state.jsonLength {
+		return 0, state.errorUnexpectedEnd()
+	}
+	return state.readIndex, nil
+}
+
+// consumeToMember enters an object and consumes it up to the start of the value of the first member called name.
+func (state *jsonValidator) consumeToMember(name string) error {
+	err := state.enterContainer()
+	if err != nil {
+		return err
+	}
+	state.readUnsafe()
+	state.consumeWhitespace()
+	if state.readIndex == state.jsonLength {
+		return state.errorUnexpectedEnd()
+	}
+	if state.readHead == '}' {
+		state.depth -= 1
+		err = state.consumeByte('}')
+		if err != nil {
+			return err
+		}
+		return ErrPathNotFound
+	}
+	for {
+		nameStart := state.readIndex
+		err = state.consumeName()
+		if err != nil {
+			return err
+		}
+		found := equalUnescaped(state.json[nameStart+1:state.readIndex-1], name)
+		state.consumeWhitespace()
+		if state.readIndex == state.jsonLength {
+			return state.errorUnexpectedEnd()
+		}
+		err = state.consumeByte(':')
+		if err != nil {
+			return err
+		}
+		state.consumeWhitespace()
+		if found {
+			return nil
+		}
+		err = state.consumeValue()
+		if err != nil {
+			return err
+		}
+		if state.readIndex == state.jsonLength {
+			return state.errorUnexpectedEnd()
+		}
+		switch state.readHead {
+		case ',':
+			state.readUnsafe()
+			state.consumeWhitespace()
+			if state.readIndex == state.jsonLength {
+				return state.errorUnexpectedEnd()
+			}
+		case '}':
+			state.depth -= 1
+			err = state.consumeByte('}')
+			if err != nil {
+				return err
+			}
+			return ErrPathNotFound
+		default:
+			return state.errorUnexpectedCharacter("any of ,}")
+		}
+	}
+}
+
+// consumeToElement enters an array and consumes it up to the start of the element at the decimal index element.
+func (state *jsonValidator) consumeToElement(element string) error {
+	err := state.enterContainer()
+	if err != nil {
+		return err
+	}
+	state.readUnsafe()
+	state.consumeWhitespace()
+	if state.readIndex == state.jsonLength {
+		return state.errorUnexpectedEnd()
+	}
+	target, isIndex := parseArrayIndex(element)
+	if state.readHead == ']' {
+		state.depth -= 1
+		err = state.consumeByte(']')
+		if err != nil {
+			return err
+		}
+		return ErrPathNotFound
+	}
+	for index := 0; ; index++ {
+		if isIndex && index == target {
+			return nil
+		}
+		err = state.consumeValue()
+		if err != nil {
+			return err
+		}
+		if state.readIndex == state.jsonLength {
+			return state.errorUnexpectedEnd()
+		}
+		switch state.readHead {
+		case ',':
+			state.readUnsafe()
+			state.consumeWhitespace()
+			if state.readIndex == state.jsonLength {
+				return state.errorUnexpectedEnd()
+			}
+		case ']':
+			state.depth -= 1
+			err = state.consumeByte(']')
+			if err != nil {
+				return err
+			}
+			return ErrPathNotFound
+		default:
+			return state.errorUnexpectedCharacter("any of ,]")
+		}
+	}
+}
+
+// parseArrayIndex parses element as the decimal index of an element of an array, without a sign or leading zeros.
+func parseArrayIndex(element string) (int, bool) {
+	if len(element) == 0 || len(element) > 18 || (element[0] == '0' && len(element) > 1) {
+		return 0, false
+	}
+	index := 0
+	for _, digit := range []byte(element) {
+		if digit < '0' || digit > '9' {
+			return 0, false
+		}
+		index = index*10 + int(digit-'0')
+	}
+	return index, true
+}
+
+// valueEnd returns the index following the last byte of the value which was just consumed, excluding any whitespace
+// consumed after it.
+func (state *jsonValidator) valueEnd() int {
+	json, end := state.json, state.readIndex
+	for json[end-1] == ' ' || json[end-1] == '\t' || json[end-1] == '\n' || json[end-1] == '\r' {
+		end -= 1
+	}
+	return end
+}
+
+// pathsGetter tracks the progress of GetMany through its paths. The path at each index of paths is done once its value
+// has been found, which is then held at the same index of values, or once it can no longer be found.
+type pathsGetter struct {
+	paths     [][]string
+	values    [][]byte
+	done      []bool
+	remaining int
+	// active holds, for each value on the path from the root to the value being consumed, the indexes of the paths
+	// which lead to or through it, those of each value following those of its container.
+	active []int
+}
+
+// errAllPathsFound is returned to stop consuming JSON once every path of a pathsGetter is done.
+var errAllPathsFound = errors.New("all paths found")
+
+func (getter *pathsGetter) finish(pathIndex int) {
+	if !getter.done[pathIndex] {
+		getter.done[pathIndex] = true
+		getter.remaining -= 1
+	}
+}
+
+// consumeValueOnPaths consumes a value which the paths in getter.active from activeStart lead to or through, having
+// matched their first depth elements, and then finishes them.
+func (state *jsonValidator) consumeValueOnPaths(getter *pathsGetter, activeStart int, depth int) error {
+	state.consumeWhitespace()
+	if state.readIndex == state.jsonLength {
+		return state.errorUnexpectedEnd()
+	}
+	valueStart := state.readIndex
+	leadsThrough := false
+	for _, pathIndex := range getter.active[activeStart:] {
+		if len(getter.paths[pathIndex]) > depth {
+			leadsThrough = true
+		}
+	}
+	var err error
+	switch {
+	case leadsThrough && state.readHead == '{':
+		err = state.consumeObjectOnPaths(getter, activeStart, depth)
+	case leadsThrough && state.readHead == '[':
+		err = state.consumeArrayOnPaths(getter, activeStart, depth)
+	default:
+		err = state.consumeValue()
+	}
+	if err != nil {
+		return err
+	}
+	state.consumeWhitespace()
+	for _, pathIndex := range getter.active[activeStart:] {
+		if len(getter.paths[pathIndex]) == depth {
+			getter.values[pathIndex] = state.json[valueStart:state.valueEnd()]
+		}
+		getter.finish(pathIndex)
+	}
+	if getter.remaining == 0 {
+		return errAllPathsFound
+	}
+	return nil
+}
+
+// consumeObjectOnPaths consumes an object, following the paths in getter.active from activeStart into the values of
+// its members.
+func (state *jsonValidator) consumeObjectOnPaths(getter *pathsGetter, activeStart int, depth int) error {
+	err := state.enterContainer()
+	if err != nil {
+		return err
+	}
+	state.readUnsafe()
+	state.consumeWhitespace()
+	if state.readIndex == state.jsonLength {
+		return state.errorUnexpectedEnd()
+	}
+	if state.readHead == '}' {
+		state.depth -= 1
+		return state.consumeByte('}')
+	}
+	for {
+		nameStart := state.readIndex
+		err = state.consumeName()
+		if err != nil {
+			return err
+		}
+		name := state.json[nameStart+1 : state.readIndex-1]
+		childStart := len(getter.active)
+		for _, pathIndex := range getter.active[activeStart:childStart] {
+			path := getter.paths[pathIndex]
+			if len(path) > depth && !getter.done[pathIndex] && equalUnescaped(name, path[depth]) {
+				getter.active = append(getter.active, pathIndex)
+			}
+		}
+		state.consumeWhitespace()
+		if state.readIndex == state.jsonLength {
+			return state.errorUnexpectedEnd()
+		}
+		err = state.consumeByte(':')
+		if err != nil {
+			return err
+		}
+		if len(getter.active) == childStart {
+			err = state.consumeValue()
+		} else {
+			err = state.consumeValueOnPaths(getter, childStart, depth+1)
+		}
+		getter.active = getter.active[:childStart]
+		if err != nil {
+			return err
+		}
+		if state.readIndex == state.jsonLength {
+			return state.errorUnexpectedEnd()
+		}
+		switch state.readHead {
+		case ',':
+			state.readUnsafe()
+			state.consumeWhitespace()
+			if state.readIndex == state.jsonLength {
+				return state.errorUnexpectedEnd()
+			}
+		case '}':
+			state.depth -= 1
+			return state.consumeByte('}')
+		default:
+			return state.errorUnexpectedCharacter("any of ,}")
+		}
+	}
+}
+
+// consumeArrayOnPaths consumes an array, following the paths in getter.active from activeStart into its elements.
+func (state *jsonValidator) consumeArrayOnPaths(getter *pathsGetter, activeStart int, depth int) error {
+	err := state.enterContainer()
+	if err != nil {
+		return err
+	}
+	state.readUnsafe()
+	state.consumeWhitespace()
+	if state.readIndex == state.jsonLength {
+		return state.errorUnexpectedEnd()
+	}
+	if state.readHead == ']' {
+		state.depth -= 1
+		return state.consumeByte(']')
+	}
+	for index := 0; ; index++ {
+		childStart := len(getter.active)
+		for _, pathIndex := range getter.active[activeStart:childStart] {
+			path := getter.paths[pathIndex]
+			if len(path) > depth && !getter.done[pathIndex] {
+				if target, isIndex := parseArrayIndex(path[depth]); isIndex && target == index {
+					getter.active = append(getter.active, pathIndex)
+				}
+			}
+		}
+		if len(getter.active) == childStart {
+			err = state.consumeValue()
+		} else {
+			err = state.consumeValueOnPaths(getter, childStart, depth+1)
+		}
+		getter.active = getter.active[:childStart]
+		if err != nil {
+			return err
+		}
+		if state.readIndex == state.jsonLength {
+			return state.errorUnexpectedEnd()
+		}
+		switch state.readHead {
+		case ',':
+			state.readUnsafe()
+		case ']':
+			state.depth -= 1
+			return state.consumeByte(']')
+		default:
+			return state.errorUnexpectedCharacter("any of ,]")
+		}
+	}
+}
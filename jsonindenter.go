@@ -0,0 +1,336 @@
+package jsonbytes
+
+import (
+	"bytes"
+	"slices"
+)
+
+// jsonIndenter appends json to output laid out as configured by options. output must not overlap json, as the output
+// is usually longer than json.
+type jsonIndenter struct {
+	// jsonValidator is held by value for the same reason as in jsonRedactor.
+	jsonValidator jsonValidator
+	output        []byte
+	options       *IndentOptions
+	// depth is the number of indents at the start of each new line, which differs from jsonValidator.depth when an
+	// array is laid out on a single line.
+	depth int
+	// lineStart is the index in output of the start of the line being written, which is used to measure its width.
+	lineStart int
+	// members holds the members of every object being written when options.SortKeys is set, those of each object
+	// following those of the objects it is nested inside. sortBuffer and nameBuffers are reused when sorting them.
+	members     []indentedMember
+	sortBuffer  []byte
+	nameBuffers [2][]byte
+}
+
+// indentedMember records where a member of an object was written in output: its name, including quotes, starts at
+// start and ends at nameEnd, and its value ends at end.
+type indentedMember struct {
+	start   int
+	nameEnd int
+	end     int
+}
+
+func newJsonIndenter(json []byte, output []byte, options *IndentOptions) (*jsonIndenter, error) {
+	jsonValidator, err := newJsonValidator(json, options.Options)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonIndenter{
+		jsonValidator: *jsonValidator,
+		output:        output,
+		options:       options,
+		lineStart:     len(output),
+	}, nil
+}
+
+// indent consumes the whole of json, and returns the output if it was a valid JSON value.
+func (state *jsonIndenter) indent() ([]byte, error) {
+	err := state.consumeValue()
+	if err != nil {
+		return nil, err
+	}
+	if state.jsonValidator.readIndex != state.jsonValidator.jsonLength {
+		return nil, state.jsonValidator.errorTrailingData()
+	}
+	return state.output, nil
+}
+
+func (state *jsonIndenter) consumeValue() error {
+	state.jsonValidator.consumeWhitespace()
+	if state.jsonValidator.readIndex == state.jsonValidator.jsonLength {
+		return state.jsonValidator.errorUnexpectedEnd()
+	}
+	var err error
+	switch state.jsonValidator.readHead {
+	case '{':
+		err = state.consumeObject()
+	case '[':
+		err = state.consumeArray()
+	default:
+		err = state.consumeScalar()
+	}
+	if err != nil {
+		return err
+	}
+	state.jsonValidator.consumeWhitespace()
+	return nil
+}
+
+// consumeScalar consumes a value which is not an object or array, and writes it verbatim.
+func (state *jsonIndenter) consumeScalar() error {
+	valueStart := state.jsonValidator.readIndex
+	var err error
+	switch state.jsonValidator.readHead {
+	case '"':
+		err = state.jsonValidator.consumeString()
+	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		err = state.jsonValidator.consumeNumber()
+	case 't':
+		err = state.jsonValidator.consumeTrue()
+	case 'f':
+		err = state.jsonValidator.consumeFalse()
+	case 'n':
+		err = state.jsonValidator.consumeNull()
+	default:
+		return state.jsonValidator.errorUnexpectedCharacter("any of \"10123456789{[tfn")
+	}
+	if err != nil {
+		return err
+	}
+	state.output = append(state.output, state.jsonValidator.json[valueStart:state.jsonValidator.readIndex]...)
+	return nil
+}
+
+func (state *jsonIndenter) consumeObject() error {
+	err := state.jsonValidator.enterContainer()
+	if err != nil {
+		return err
+	}
+	state.writeUnsafe()
+	state.jsonValidator.consumeWhitespace()
+	if state.jsonValidator.readIndex == state.jsonValidator.jsonLength {
+		return state.jsonValidator.errorUnexpectedEnd()
+	}
+	if state.jsonValidator.readHead == '}' {
+		state.jsonValidator.depth -= 1
+		return state.consumeByte('}')
+	}
+	state.depth += 1
+	membersStart := len(state.members)
+	for {
+		state.writeNewline()
+		memberStart := len(state.output)
+		nameStart := state.jsonValidator.readIndex
+		err = state.jsonValidator.consumeName()
+		if err != nil {
+			return err
+		}
+		state.output = append(state.output, state.jsonValidator.json[nameStart:state.jsonValidator.readIndex]...)
+		nameEnd := len(state.output)
+		state.jsonValidator.consumeWhitespace()
+		if state.jsonValidator.readIndex == state.jsonValidator.jsonLength {
+			return state.jsonValidator.errorUnexpectedEnd()
+		}
+		err = state.consumeByte(':')
+		if err != nil {
+			return err
+		}
+		state.output = append(state.output, ' ')
+		err = state.consumeValue()
+		if err != nil {
+			return err
+		}
+		if state.options.SortKeys {
+			member := indentedMember{start: memberStart, nameEnd: nameEnd, end: len(state.output)}
+			state.members = append(state.members, member)
+		}
+		if state.jsonValidator.readIndex == state.jsonValidator.jsonLength {
+			return state.jsonValidator.errorUnexpectedEnd()
+		}
+		switch state.jsonValidator.readHead {
+		case ',':
+			state.writeUnsafe()
+			state.jsonValidator.consumeWhitespace()
+			if state.jsonValidator.readIndex == state.jsonValidator.jsonLength {
+				return state.jsonValidator.errorUnexpectedEnd()
+			}
+		case '}':
+			if state.options.SortKeys {
+				state.sortMembers(membersStart)
+			}
+			state.depth -= 1
+			state.writeNewline()
+			state.jsonValidator.depth -= 1
+			return state.consumeByte('}')
+		default:
+			return state.jsonValidator.errorUnexpectedCharacter("any of ,}")
+		}
+	}
+}
+
+// sortMembers sorts the members of the object starting at membersStart by name, rewriting them in output if they were
+// not already sorted, and removes them from members.
+func (state *jsonIndenter) sortMembers(membersStart int) {
+	members := state.members[membersStart:]
+	state.members = state.members[:membersStart]
+	if slices.IsSortedFunc(members, state.compareMembers) {
+		return
+	}
+	// The members are written in output in their original order, separated by identical separators, so they are
+	// copied aside and written back in sorted order.
+	regionStart := members[0].start
+	state.sortBuffer = append(state.sortBuffer[:0], state.output[regionStart:members[len(members)-1].end]...)
+	separator := state.sortBuffer[members[0].end-regionStart : members[1].start-regionStart]
+	slices.SortStableFunc(members, state.compareMembers)
+	state.output = state.output[:regionStart]
+	for index, member := range members {
+		if index != 0 {
+			state.output = append(state.output, separator...)
+		}
+		state.output = append(state.output, state.sortBuffer[member.start-regionStart:member.end-regionStart]...)
+	}
+}
+
+// compareMembers compares the names of two members written in output after their escape sequences have been decoded,
+// so that members are sorted in the same order as encoding/json sorts the keys of maps.
+func (state *jsonIndenter) compareMembers(a indentedMember, b indentedMember) int {
+	aName := state.output[a.start+1 : a.nameEnd-1]
+	bName := state.output[b.start+1 : b.nameEnd-1]
+	if bytes.IndexByte(aName, '\\') != -1 || bytes.IndexByte(bName, '\\') != -1 {
+		state.nameBuffers[0] = appendUnescaped(state.nameBuffers[0][:0], aName)
+		state.nameBuffers[1] = appendUnescaped(state.nameBuffers[1][:0], bName)
+		aName, bName = state.nameBuffers[0], state.nameBuffers[1]
+	}
+	return bytes.Compare(aName, bName)
+}
+
+func (state *jsonIndenter) consumeArray() error {
+	if state.options.CompactArrays {
+		arrayStart, outputStart := state.jsonValidator.readIndex, len(state.output)
+		fits, err := state.consumeCompactArray()
+		if err != nil || fits {
+			return err
+		}
+		// The array is consumed again from its start, to be laid out over multiple lines instead.
+		state.jsonValidator.readIndex = arrayStart
+		state.jsonValidator.readHead = '['
+		state.jsonValidator.depth -= 1
+		state.output = state.output[:outputStart]
+	}
+	err := state.jsonValidator.enterContainer()
+	if err != nil {
+		return err
+	}
+	state.writeUnsafe()
+	state.jsonValidator.consumeWhitespace()
+	if state.jsonValidator.readIndex == state.jsonValidator.jsonLength {
+		return state.jsonValidator.errorUnexpectedEnd()
+	}
+	if state.jsonValidator.readHead == ']' {
+		state.jsonValidator.depth -= 1
+		return state.consumeByte(']')
+	}
+	state.depth += 1
+	for {
+		state.writeNewline()
+		err = state.consumeValue()
+		if err != nil {
+			return err
+		}
+		if state.jsonValidator.readIndex == state.jsonValidator.jsonLength {
+			return state.jsonValidator.errorUnexpectedEnd()
+		}
+		switch state.jsonValidator.readHead {
+		case ',':
+			state.writeUnsafe()
+		case ']':
+			state.depth -= 1
+			state.writeNewline()
+			state.jsonValidator.depth -= 1
+			return state.consumeByte(']')
+		default:
+			return state.jsonValidator.errorUnexpectedCharacter("any of ,]")
+		}
+	}
+}
+
+// consumeCompactArray consumes an array and writes it on a single line, unless it contains an object or array, or the
+// line would be wider than options.MaxWidth, in which case it returns false having consumed and written only part of
+// it, and having entered it.
+func (state *jsonIndenter) consumeCompactArray() (bool, error) {
+	err := state.jsonValidator.enterContainer()
+	if err != nil {
+		return false, err
+	}
+	state.writeUnsafe()
+	state.jsonValidator.consumeWhitespace()
+	if state.jsonValidator.readIndex == state.jsonValidator.jsonLength {
+		return false, state.jsonValidator.errorUnexpectedEnd()
+	}
+	if state.jsonValidator.readHead == ']' {
+		state.jsonValidator.depth -= 1
+		return true, state.consumeByte(']')
+	}
+	for {
+		if state.jsonValidator.readHead == '{' || state.jsonValidator.readHead == '[' || state.tooWide() {
+			return false, nil
+		}
+		err = state.consumeScalar()
+		if err != nil {
+			return false, err
+		}
+		state.jsonValidator.consumeWhitespace()
+		if state.jsonValidator.readIndex == state.jsonValidator.jsonLength {
+			return false, state.jsonValidator.errorUnexpectedEnd()
+		}
+		switch state.jsonValidator.readHead {
+		case ',':
+			state.writeUnsafe()
+			state.output = append(state.output, ' ')
+			state.jsonValidator.consumeWhitespace()
+			if state.jsonValidator.readIndex == state.jsonValidator.jsonLength {
+				return false, state.jsonValidator.errorUnexpectedEnd()
+			}
+		case ']':
+			state.jsonValidator.depth -= 1
+			err = state.consumeByte(']')
+			if err != nil || state.tooWide() {
+				// The array is left entered, as it will be consumed again from its start.
+				state.jsonValidator.depth += 1
+				return false, err
+			}
+			return true, nil
+		default:
+			return false, state.jsonValidator.errorUnexpectedCharacter("any of ,]")
+		}
+	}
+}
+
+func (state *jsonIndenter) tooWide() bool {
+	return state.options.MaxWidth > 0 && len(state.output)-state.lineStart > state.options.MaxWidth
+}
+
+func (state *jsonIndenter) consumeByte(expectedByte byte) error {
+	err := state.jsonValidator.consumeByte(expectedByte)
+	if err != nil {
+		return err
+	}
+	state.output = append(state.output, expectedByte)
+	return nil
+}
+
+func (state *jsonIndenter) writeNewline() {
+	state.output = append(state.output, '\n')
+	state.lineStart = len(state.output)
+	state.output = append(state.output, state.options.Prefix...)
+	for range state.depth {
+		state.output = append(state.output, state.options.Indentation...)
+	}
+}
+
+func (state *jsonIndenter) writeUnsafe() {
+	state.output = append(state.output, state.jsonValidator.readHead)
+	state.jsonValidator.readUnsafe()
+}
@@ -0,0 +1,282 @@
+package jsonbytes
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+// inferredTypes is a set of the types of the values observed at the same place in samples, named as they are in the
+// type keyword of a JSON Schema.
+type inferredTypes int
+
+const (
+	inferredNull inferredTypes = 1 << iota
+	inferredBoolean
+	inferredObject
+	inferredArray
+	// inferredInteger is set for numbers written without a fraction or exponent, and inferredNumber for any other.
+	inferredInteger
+	inferredNumber
+	inferredString
+)
+
+var inferredTypeNames = []string{"null", "boolean", "object", "array", "integer", "number", "string"}
+
+// stringFormats is a set of the string formats of JSON Schema which InferSchema recognises, although a string is in at
+// most one of them.
+type stringFormats int
+
+const (
+	formatDateTime stringFormats = 1 << iota
+	formatDate
+	formatTime
+	formatUUID
+)
+
+var stringFormatNames = []string{"date-time", "date", "time", "uuid"}
+
+// inferredShape is the union of the shapes of the values observed at the same place in samples.
+type inferredShape struct {
+	types inferredTypes
+	// objects is the number of objects observed, and properties are the members of any of them, in the order they were
+	// first observed.
+	objects         int
+	properties      []*inferredProperty
+	propertyIndexes map[string]int
+	// items is the union of the shapes of the elements of every array observed, or nil if they were all empty.
+	items *inferredShape
+	// formats is the format which every string observed is in, or zero if there is none.
+	formats stringFormats
+}
+
+type inferredProperty struct {
+	name  string
+	shape inferredShape
+	// count is the number of objects the member was observed in, and lastObject is the number of the object it was
+	// last observed in, so that it is only counted once in an object in which its name appears more than once.
+	count      int
+	lastObject int
+}
+
+// schemaInferrer records the shapes of samples as it consumes them. The samples must already have been validated, so
+// the errors returned when consuming them are ignored.
+type schemaInferrer struct {
+	root    inferredShape
+	objects int
+	// decodeBuffer is reused to decode names and strings.
+	decodeBuffer []byte
+}
+
+// observeValue consumes the value at the start of state, recording its shape in shape.
+func (inferrer *schemaInferrer) observeValue(state *jsonValidator, shape *inferredShape) {
+	valueStart := state.readIndex
+	switch state.readHead {
+	case '{':
+		shape.types |= inferredObject
+		inferrer.observeObject(state, shape)
+	case '[':
+		shape.types |= inferredArray
+		inferrer.observeArray(state, shape)
+	case '"':
+		state.consumeString()
+		inferrer.decodeBuffer = appendUnescaped(inferrer.decodeBuffer[:0], state.json[valueStart+1:state.readIndex-1])
+		format := detectStringFormat(inferrer.decodeBuffer)
+		if shape.types&inferredString == 0 {
+			shape.formats = format
+		} else {
+			shape.formats &= format
+		}
+		shape.types |= inferredString
+	case 't', 'f':
+		shape.types |= inferredBoolean
+		state.consumeValue()
+	case 'n':
+		shape.types |= inferredNull
+		state.consumeValue()
+	default:
+		state.consumeNumber()
+		shape.types |= inferredInteger
+		for _, character := range state.json[valueStart:state.readIndex] {
+			if character == '.' || character == 'e' || character == 'E' {
+				shape.types |= inferredNumber
+			}
+		}
+	}
+	state.consumeWhitespace()
+}
+
+func (inferrer *schemaInferrer) observeObject(state *jsonValidator, shape *inferredShape) {
+	inferrer.objects += 1
+	object := inferrer.objects
+	shape.objects += 1
+	state.readUnsafe()
+	state.consumeWhitespace()
+	for state.readHead != '}' {
+		nameStart := state.readIndex
+		state.consumeName()
+		inferrer.decodeBuffer = appendUnescaped(inferrer.decodeBuffer[:0], state.json[nameStart+1:state.readIndex-1])
+		propertyIndex, found := shape.propertyIndexes[string(inferrer.decodeBuffer)]
+		if !found {
+			if shape.propertyIndexes == nil {
+				shape.propertyIndexes = make(map[string]int)
+			}
+			propertyIndex = len(shape.properties)
+			shape.propertyIndexes[string(inferrer.decodeBuffer)] = propertyIndex
+			shape.properties = append(shape.properties, &inferredProperty{name: string(inferrer.decodeBuffer)})
+		}
+		property := shape.properties[propertyIndex]
+		if property.lastObject != object {
+			property.count += 1
+			property.lastObject = object
+		}
+		state.consumeWhitespace()
+		state.readUnsafe()
+		state.consumeWhitespace()
+		inferrer.observeValue(state, &property.shape)
+		if state.readHead == ',' {
+			state.readUnsafe()
+			state.consumeWhitespace()
+		}
+	}
+	state.readUnsafe()
+}
+
+func (inferrer *schemaInferrer) observeArray(state *jsonValidator, shape *inferredShape) {
+	state.readUnsafe()
+	state.consumeWhitespace()
+	for state.readHead != ']' {
+		if shape.items == nil {
+			shape.items = &inferredShape{}
+		}
+		inferrer.observeValue(state, shape.items)
+		if state.readHead == ',' {
+			state.readUnsafe()
+			state.consumeWhitespace()
+		}
+	}
+	state.readUnsafe()
+}
+
+// appendSchema appends a JSON Schema describing shape to dst, and returns the extended buffer.
+func (shape *inferredShape) appendSchema(dst []byte) []byte {
+	dst = append(dst, '{')
+	dst = shape.appendKeywords(dst)
+	return append(dst, '}')
+}
+
+// appendKeywords appends the keywords of a JSON Schema describing shape to dst, without the braces around them, and
+// returns the extended buffer. The type keyword is always first.
+func (shape *inferredShape) appendKeywords(dst []byte) []byte {
+	types := shape.types
+	if types&inferredNumber != 0 {
+		// Every integer is a number, so the type of a value which was observed to be both is a number.
+		types &^= inferredInteger
+	}
+	dst = append(dst, `"type":`...)
+	dst = appendNames(dst, inferredTypeNames, int(types))
+	if shape.formats != 0 {
+		dst = append(dst, `,"format":`...)
+		dst = appendNames(dst, stringFormatNames, int(shape.formats))
+	}
+	if shape.objects != 0 {
+		dst = append(dst, `,"properties":{`...)
+		for index, property := range shape.properties {
+			if index != 0 {
+				dst = append(dst, ',')
+			}
+			dst = append(dst, '"')
+			dst = appendEscaped(dst, []byte(property.name))
+			dst = append(dst, `":{`...)
+			dst = property.shape.appendKeywords(dst)
+			// The fraction of the objects the member was in goes in its schema, as an annotation which validators
+			// ignore, since there is no keyword for it.
+			frequency := math.Round(float64(property.count)/float64(shape.objects)*1000) / 1000
+			dst = append(dst, `,"x-frequency":`...)
+			dst = strconv.AppendFloat(dst, frequency, 'g', -1, 64)
+			dst = append(dst, '}')
+		}
+		dst = append(dst, `},"required":[`...)
+		required := 0
+		for _, property := range shape.properties {
+			if property.count == shape.objects {
+				if required != 0 {
+					dst = append(dst, ',')
+				}
+				required += 1
+				dst = append(dst, '"')
+				dst = appendEscaped(dst, []byte(property.name))
+				dst = append(dst, '"')
+			}
+		}
+		dst = append(dst, ']')
+	}
+	if shape.items != nil {
+		dst = append(dst, `,"items":`...)
+		dst = shape.items.appendSchema(dst)
+	}
+	return dst
+}
+
+// appendNames appends the names of the bits set in set to dst, as a JSON string if there is one, or else as an array of
+// them, and returns the extended buffer.
+func appendNames(dst []byte, names []string, set int) []byte {
+	isArray := set&(set-1) != 0
+	if isArray {
+		dst = append(dst, '[')
+	}
+	count := 0
+	for index, name := range names {
+		if set&(1<<index) != 0 {
+			if count != 0 {
+				dst = append(dst, ',')
+			}
+			count += 1
+			dst = append(dst, '"')
+			dst = append(dst, name...)
+			dst = append(dst, '"')
+		}
+	}
+	if isArray {
+		dst = append(dst, ']')
+	}
+	return dst
+}
+
+// detectStringFormat returns the format which value, a decoded string, is in, or zero if it is in none of them. The
+// cheap checks of each case rule out most strings before they are parsed.
+func detectStringFormat(value []byte) stringFormats {
+	var err error
+	switch {
+	case len(value) >= 20 && value[10] == 'T':
+		if _, err = time.Parse(time.RFC3339Nano, string(value)); err == nil {
+			return formatDateTime
+		}
+	case len(value) == 10 && value[4] == '-':
+		if _, err = time.Parse(time.DateOnly, string(value)); err == nil {
+			return formatDate
+		}
+	case len(value) >= 9 && value[2] == ':':
+		if _, err = time.Parse("15:04:05.999999999Z07:00", string(value)); err == nil {
+			return formatTime
+		}
+	case len(value) == 36 && isUUID(value):
+		return formatUUID
+	}
+	return 0
+}
+
+// isUUID reports whether value, which must be 36 bytes long, is a UUID in its hyphenated hexadecimal form.
+func isUUID(value []byte) bool {
+	for index, character := range value {
+		if index == 8 || index == 13 || index == 18 || index == 23 {
+			if character != '-' {
+				return false
+			}
+		} else if !('0' <= character && character <= '9' || 'a' <= character && character <= 'f' ||
+			'A' <= character && character <= 'F') {
+			return false
+		}
+	}
+	return true
+}
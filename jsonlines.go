@@ -0,0 +1,117 @@
+package jsonbytes
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+)
+
+// jsonLinesReader splits JSON Lines into lines, either from jsonLines or, if it is set, from reader, in which case each
+// line is held in lineBuffer in turn.
+type jsonLinesReader struct {
+	jsonLines  []byte
+	reader     *bufio.Reader
+	lineBuffer []byte
+}
+
+// next returns the next line without its '\n', and whether it had one, or io.EOF once there are no more lines.
+func (lines *jsonLinesReader) next() ([]byte, bool, error) {
+	if lines.reader == nil {
+		if len(lines.jsonLines) == 0 {
+			return nil, false, io.EOF
+		}
+		lineEnd := bytes.IndexByte(lines.jsonLines, '\n')
+		if lineEnd == -1 {
+			line := lines.jsonLines
+			lines.jsonLines = nil
+			return line, false, nil
+		}
+		line := lines.jsonLines[:lineEnd]
+		lines.jsonLines = lines.jsonLines[lineEnd+1:]
+		return line, true, nil
+	}
+	lines.lineBuffer = lines.lineBuffer[:0]
+	for {
+		chunk, err := lines.reader.ReadSlice('\n')
+		lines.lineBuffer = append(lines.lineBuffer, chunk...)
+		switch {
+		case err == bufio.ErrBufferFull:
+			continue
+		case err == io.EOF && len(lines.lineBuffer) != 0:
+			return lines.lineBuffer, false, nil
+		case err != nil:
+			return nil, false, err
+		}
+		return lines.lineBuffer[:len(lines.lineBuffer)-1], true, nil
+	}
+}
+
+// jsonLinesProcessor validates each record of JSON Lines, and redacts it into output if redact is set. If writer is
+// set, output is written to it after each line.
+type jsonLinesProcessor struct {
+	options JsonLinesOptions
+	redact  bool
+	output  []byte
+	writer  io.Writer
+	errs    []error
+}
+
+// process processes every line of lines, and returns the errors of the records which were not valid, or the first of
+// them unless options.ContinueOnError is set, or the error returned by the reader or writer.
+func (processor *jsonLinesProcessor) process(lines *jsonLinesReader) error {
+	for lineNumber := 1; ; lineNumber++ {
+		line, hasNewline, err := lines.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.Join(append(processor.errs, err)...)
+		}
+		err = processor.processLine(line)
+		if err != nil {
+			processor.errs = append(processor.errs, &LineError{Line: lineNumber, Err: err})
+			if !processor.options.ContinueOnError {
+				return processor.errs[0]
+			}
+		}
+		if processor.redact && hasNewline {
+			processor.output = append(processor.output, '\n')
+		}
+		if processor.writer != nil && len(processor.output) != 0 {
+			_, err = processor.writer.Write(processor.output)
+			if err != nil {
+				return errors.Join(append(processor.errs, err)...)
+			}
+			processor.output = processor.output[:0]
+		}
+	}
+	if len(processor.errs) == 1 {
+		return processor.errs[0]
+	}
+	return errors.Join(processor.errs...)
+}
+
+// processLine validates a single line, and redacts it into output if redact is set. Blank lines are not records, so
+// they are skipped, but are kept in output so that the line numbers of the records which follow them are unchanged.
+func (processor *jsonLinesProcessor) processLine(line []byte) error {
+	if len(bytes.Trim(line, " \t\r")) == 0 {
+		if processor.redact {
+			processor.output = append(processor.output, line...)
+		}
+		return nil
+	}
+	if !processor.redact {
+		return processor.options.Options.IsJson(line)
+	}
+	redactedJson, err := processor.options.Options.RedactAllValuesTo(processor.output, line)
+	if err != nil {
+		return err
+	}
+	processor.output = redactedJson
+	// The '\r' of a "\r\n" line ending is whitespace within the record, so it is removed by the redaction.
+	if line[len(line)-1] == '\r' {
+		processor.output = append(processor.output, '\r')
+	}
+	return nil
+}
@@ -1,27 +1,57 @@
 package jsonbytes
 
-import "errors"
-
+// jsonRedactor appends its output to output. To redact json in place, output can be json[:0], as the output never
+// grows faster than json is read, so appending never overwrites bytes which are yet to be read. If matcher is nil,
+// every value is redacted, else only those it decides are. If strategies is nil, redacted values are replaced in the
+// same manner as RedactAllValues, else by its strategies, which must all be set. If keepValues is set, no value is
+// redacted, so that json is only compacted. Whitespace is removed unless preserveWhitespace is set.
 type jsonRedactor struct {
-	jsonValidator *jsonValidator
-	writeIndex    int
+	// jsonValidator is held by value, as escape analysis would otherwise move it to the heap because of the appends to
+	// output.
+	jsonValidator      jsonValidator
+	output             []byte
+	matcher            *redactRulesMatcher
+	strategies         *Redactor
+	keepValues         bool
+	preserveWhitespace bool
+	// inPlace is set when output is json[:0], so that json is overwritten as it is read.
+	inPlace bool
 }
 
-func newJsonRedactor(json []byte) (*jsonRedactor, error) {
-	jsonValidator, err := newJsonValidator(json)
+// newJsonRedactor returns a jsonRedactor by value, so that it stays off the heap without having to be inlined.
+func newJsonRedactor(json []byte, output []byte, options Options) (jsonRedactor, error) {
+	jsonValidator, err := newJsonValidator(json, options)
 	if err != nil {
-		return nil, err
+		return jsonRedactor{}, err
 	}
-	return &jsonRedactor{
-		jsonValidator: jsonValidator,
-		writeIndex:    0,
+	inPlace := cap(output) != 0 && &output[:1][0] == &json[0]
+	// Redacting in place overwrites names with the output, so they must be copied to be checked for duplicates.
+	if jsonValidator.uniqueNames != nil && inPlace {
+		jsonValidator.uniqueNames.copyNames = true
+	}
+	return jsonRedactor{
+		jsonValidator: *jsonValidator,
+		output:        output,
+		inPlace:       inPlace,
 	}, nil
 }
 
+// redact consumes the whole of json, and returns the output if it was a valid JSON value.
+func (state *jsonRedactor) redact() ([]byte, error) {
+	err := state.consumeValue()
+	if err != nil {
+		return nil, err
+	}
+	if state.jsonValidator.readIndex != state.jsonValidator.jsonLength {
+		return nil, state.jsonValidator.errorTrailingData()
+	}
+	return state.output, nil
+}
+
 func (state *jsonRedactor) consumeValue() error {
-	state.jsonValidator.consumeWhitespace()
+	state.consumeWhitespace()
 	if state.jsonValidator.readIndex == state.jsonValidator.jsonLength {
-		return errors.New("read head ran out of json")
+		return state.jsonValidator.errorUnexpectedEnd()
 	}
 	var err error
 	switch state.jsonValidator.readHead {
@@ -45,140 +75,198 @@ func (state *jsonRedactor) consumeValue() error {
 	if err != nil {
 		return err
 	}
-	state.jsonValidator.consumeWhitespace()
+	state.consumeWhitespace()
 	return nil
 }
 
 func (state *jsonRedactor) consumeObject() error {
+	err := state.jsonValidator.enterContainer()
+	if err != nil {
+		return err
+	}
 	state.writeUnsafe()
-	state.jsonValidator.consumeWhitespace()
+	state.consumeWhitespace()
 	if state.jsonValidator.readIndex == state.jsonValidator.jsonLength {
-		return errors.New("read head ran out of json")
+		return state.jsonValidator.errorUnexpectedEnd()
+	}
+	if state.jsonValidator.readHead == '}' {
+		state.jsonValidator.depth -= 1
+		return state.consumeByte('}')
 	}
-	var err error
 	for {
+		outputNameStart := len(state.output)
+		err = state.consumeName()
+		if err != nil {
+			return err
+		}
+		if state.matcher != nil {
+			// The name is read from the output, as it may already have been overwritten in json.
+			state.matcher.enterMember(state.output[outputNameStart+1 : len(state.output)-1])
+		}
+		state.consumeWhitespace()
+		if state.jsonValidator.readIndex == state.jsonValidator.jsonLength {
+			return state.jsonValidator.errorUnexpectedEnd()
+		}
+		err = state.consumeByte(':')
+		if err != nil {
+			return err
+		}
+		err = state.consumeValue()
+		if err != nil {
+			return err
+		}
+		if state.matcher != nil {
+			state.matcher.leave()
+		}
+		if state.jsonValidator.readIndex == state.jsonValidator.jsonLength {
+			return state.jsonValidator.errorUnexpectedEnd()
+		}
 		switch state.jsonValidator.readHead {
 		case ',':
-			err = state.consumeByte(',')
-			if err != nil {
-				return err
+			state.writeUnsafe()
+			state.consumeWhitespace()
+			if state.jsonValidator.readIndex == state.jsonValidator.jsonLength {
+				return state.jsonValidator.errorUnexpectedEnd()
 			}
 		case '}':
+			state.jsonValidator.depth -= 1
 			return state.consumeByte('}')
 		default:
-			state.jsonValidator.consumeWhitespace()
-			if state.jsonValidator.readIndex == state.jsonValidator.jsonLength {
-				return errors.New("read head ran out of json")
-			}
-			err = state.consumeName()
-			if err != nil {
-				return err
-			}
-			state.jsonValidator.consumeWhitespace()
-			if state.jsonValidator.readIndex == state.jsonValidator.jsonLength {
-				return errors.New("read head ran out of json")
-			}
-			err = state.consumeByte(':')
-			if err != nil {
-				return err
-			}
-			err = state.consumeValue()
-			if err != nil {
-				return err
-			}
+			return state.jsonValidator.errorUnexpectedCharacter("any of ,}")
 		}
 	}
 }
 
 func (state *jsonRedactor) consumeArray() error {
+	err := state.jsonValidator.enterContainer()
+	if err != nil {
+		return err
+	}
 	state.writeUnsafe()
-	state.jsonValidator.consumeWhitespace()
+	state.consumeWhitespace()
 	if state.jsonValidator.readIndex == state.jsonValidator.jsonLength {
-		return errors.New("read head ran out of json")
+		return state.jsonValidator.errorUnexpectedEnd()
 	}
-	var err error
-	for {
+	if state.jsonValidator.readHead == ']' {
+		state.jsonValidator.depth -= 1
+		return state.consumeByte(']')
+	}
+	for index := 0; ; index++ {
+		if state.matcher != nil {
+			state.matcher.enterElement(index)
+		}
+		err = state.consumeValue()
+		if err != nil {
+			return err
+		}
+		if state.matcher != nil {
+			state.matcher.leave()
+		}
+		if state.jsonValidator.readIndex == state.jsonValidator.jsonLength {
+			return state.jsonValidator.errorUnexpectedEnd()
+		}
 		switch state.jsonValidator.readHead {
+		case ',':
+			state.writeUnsafe()
 		case ']':
+			state.jsonValidator.depth -= 1
 			return state.consumeByte(']')
-		case ',':
-			err = state.consumeByte(',')
-			if err != nil {
-				return err
-			}
 		default:
-			err = state.consumeValue()
-			if err != nil {
-				return err
-			}
+			return state.jsonValidator.errorUnexpectedCharacter("any of ,]")
 		}
 	}
 }
 
 func (state *jsonRedactor) consumeString() error {
+	valueStart := state.jsonValidator.readIndex
 	err := state.jsonValidator.consumeString()
 	if err != nil {
 		return err
 	}
-	state.jsonValidator.json[state.writeIndex] = '"'
-	state.writeIndex += 1
-	state.jsonValidator.json[state.writeIndex] = '"'
-	state.writeIndex += 1
+	value := state.jsonValidator.json[valueStart:state.jsonValidator.readIndex]
+	if !state.isRedacting() {
+		state.output = append(state.output, value...)
+	} else if state.strategies != nil {
+		state.output = state.strategies.Strings.AppendRedacted(state.output, value)
+	} else {
+		state.output = append(state.output, '"', '"')
+	}
 	return nil
 }
 
 func (state *jsonRedactor) consumeName() error {
-	bytesConsumed := -state.jsonValidator.readIndex
-	err := state.jsonValidator.consumeString()
+	nameStart := state.jsonValidator.readIndex
+	err := state.jsonValidator.consumeName()
 	if err != nil {
 		return err
 	}
-	bytesConsumed += state.jsonValidator.readIndex
-	copy(
-		state.jsonValidator.json[state.writeIndex:state.writeIndex+bytesConsumed],
-		state.jsonValidator.json[state.jsonValidator.readIndex-bytesConsumed:state.jsonValidator.readIndex],
-	)
-	state.writeIndex += bytesConsumed
+	state.output = append(state.output, state.jsonValidator.json[nameStart:state.jsonValidator.readIndex]...)
 	return nil
 }
 
 func (state *jsonRedactor) consumeNumber() error {
+	valueStart := state.jsonValidator.readIndex
 	err := state.jsonValidator.consumeNumber()
 	if err != nil {
 		return err
 	}
-	state.jsonValidator.json[state.writeIndex] = '0'
-	state.writeIndex += 1
+	value := state.jsonValidator.json[valueStart:state.jsonValidator.readIndex]
+	if !state.isRedacting() {
+		state.output = append(state.output, value...)
+	} else if state.strategies != nil {
+		state.output = state.strategies.Numbers.AppendRedacted(state.output, value)
+	} else {
+		state.output = append(state.output, '0')
+	}
 	return nil
 }
 
 func (state *jsonRedactor) consumeTrue() error {
+	valueStart := state.jsonValidator.readIndex
 	err := state.jsonValidator.consumeTrue()
 	if err != nil {
 		return err
 	}
-	copy(state.jsonValidator.json[state.writeIndex:state.writeIndex+4], []byte("true"))
-	state.writeIndex += 4
+	value := state.jsonValidator.json[valueStart:state.jsonValidator.readIndex]
+	if !state.isRedacting() {
+		state.output = append(state.output, value...)
+	} else if state.strategies != nil {
+		state.output = state.strategies.Booleans.AppendRedacted(state.output, value)
+	} else {
+		state.output = append(state.output, "true"...)
+	}
 	return nil
 }
 
 func (state *jsonRedactor) consumeFalse() error {
+	valueStart := state.jsonValidator.readIndex
 	err := state.jsonValidator.consumeFalse()
 	if err != nil {
 		return err
 	}
-	copy(state.jsonValidator.json[state.writeIndex:state.writeIndex+4], []byte("true"))
-	state.writeIndex += 4
+	value := state.jsonValidator.json[valueStart:state.jsonValidator.readIndex]
+	if !state.isRedacting() {
+		state.output = append(state.output, value...)
+	} else if state.strategies != nil {
+		state.output = state.strategies.Booleans.AppendRedacted(state.output, value)
+	} else {
+		state.output = append(state.output, "true"...)
+	}
 	return nil
 }
 
 func (state *jsonRedactor) consumeNull() error {
+	valueStart := state.jsonValidator.readIndex
 	err := state.jsonValidator.consumeNull()
 	if err != nil {
 		return err
 	}
-	copy(state.jsonValidator.json[state.writeIndex:state.writeIndex+4], []byte("null"))
-	state.writeIndex += 4
+	if state.strategies != nil && state.isRedacting() {
+		value := state.jsonValidator.json[valueStart:state.jsonValidator.readIndex]
+		state.output = state.strategies.Nulls.AppendRedacted(state.output, value)
+	} else {
+		state.output = append(state.output, "null"...)
+	}
 	return nil
 }
 
@@ -187,15 +275,34 @@ func (state *jsonRedactor) consumeByte(expectedByte byte) error {
 	if err != nil {
 		return err
 	}
-	state.jsonValidator.json[state.writeIndex] = expectedByte
-	state.writeIndex += 1
+	state.output = append(state.output, expectedByte)
 	return nil
 }
 
-func (state *jsonRedactor) writeUnsafe() {
-	if state.writeIndex != state.jsonValidator.readIndex {
-		state.jsonValidator.json[state.writeIndex] = state.jsonValidator.readHead
+// consumeWhitespace consumes whitespace like jsonValidator.consumeWhitespace, but when redacting in place, counts any
+// newlines it consumes before they can be overwritten, so that syntax errors still report the correct line. Otherwise
+// they are left to be counted from json if there is an error. If preserveWhitespace is set, the whitespace is also
+// written to the output.
+func (state *jsonRedactor) consumeWhitespace() {
+	whitespaceStart := state.jsonValidator.readIndex
+	state.jsonValidator.consumeWhitespace()
+	whitespaceEnd := state.jsonValidator.readIndex
+	if whitespaceEnd != whitespaceStart {
+		if state.inPlace {
+			state.jsonValidator.linesCountedTo = whitespaceStart
+			state.jsonValidator.countLines(whitespaceEnd)
+		}
+		if state.preserveWhitespace {
+			state.output = append(state.output, state.jsonValidator.json[whitespaceStart:whitespaceEnd]...)
+		}
 	}
-	state.writeIndex += 1
+}
+
+func (state *jsonRedactor) isRedacting() bool {
+	return !state.keepValues && (state.matcher == nil || state.matcher.isRedacting())
+}
+
+func (state *jsonRedactor) writeUnsafe() {
+	state.output = append(state.output, state.jsonValidator.readHead)
 	state.jsonValidator.readUnsafe()
 }
@@ -0,0 +1,380 @@
+package jsonbytes
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// RedactRules selects which values Redact replaces. It is compiled from path selectors by CompileRedactRules, or from
+// name patterns by CompileKeyRedactRules, and can be reused, including concurrently, for any number of calls to Redact.
+type RedactRules struct {
+	selectors []pathSelector
+}
+
+// pathSelector is a compiled path, such as $.items[*].card.number, which matches the values whose path from the root
+// of the JSON value matches each of its segments in turn.
+type pathSelector struct {
+	segments []pathSegment
+	preserve bool
+}
+
+type pathSegmentKind int
+
+const (
+	pathSegmentName pathSegmentKind = iota
+	pathSegmentIndex
+	pathSegmentWildcard
+	pathSegmentNamePattern
+)
+
+// pathSegment matches one step from a container to one of its members or elements. If descendant is set, it was
+// written with .. and may also skip over any number of steps before the one it matches.
+type pathSegment struct {
+	kind       pathSegmentKind
+	name       string
+	index      int
+	descendant bool
+}
+
+// CompileRedactRules compiles the path selectors in redactPaths and preservePaths into RedactRules for Redact. Redact
+// replaces every value matched by a selector in redactPaths, along with every value nested inside it, except where a
+// selector in preservePaths matches the value or a container nearer to it: the nearest matching selector on the path
+// to each value decides whether it is redacted, and a preserve selector wins if both match the same value. Values not
+// matched by any selector are preserved, so redactPaths of "$" with preservePaths listing the values to keep redacts
+// everything else.
+//
+// A selector begins with $, which matches the root value, followed by any number of these segments:
+//   - .name or ['name'] matches the member called name of an object, where the bracketed form may use any characters,
+//     escaping ' and \ with a \.
+//   - [n] matches the element at index n of an array.
+//   - .* or [*] matches every member of an object and every element of an array.
+//   - ..name, ..['name'], ..[n] or ..* matches the same as the segment without the extra ., but at any depth below
+//     the value matched so far, so $..token matches a member called token anywhere.
+//
+// Names are compared with the names in the JSON after their escape sequences have been decoded. An error is returned
+// if any selector is not valid.
+func CompileRedactRules(redactPaths []string, preservePaths []string) (RedactRules, error) {
+	rules := RedactRules{selectors: make([]pathSelector, 0, len(redactPaths)+len(preservePaths))}
+	for _, path := range redactPaths {
+		segments, err := compilePath(path)
+		if err != nil {
+			return RedactRules{}, err
+		}
+		rules.selectors = append(rules.selectors, pathSelector{segments: segments})
+	}
+	for _, path := range preservePaths {
+		segments, err := compilePath(path)
+		if err != nil {
+			return RedactRules{}, err
+		}
+		rules.selectors = append(rules.selectors, pathSelector{segments: segments, preserve: true})
+	}
+	return rules, nil
+}
+
+// CompileKeyRedactRules compiles RedactRules for Redact which select values by the names of the members they belong
+// to, wherever those members appear. Redact replaces the value of every member whose name matches a pattern in
+// denylist, along with every value nested inside it, except where a member nearer to the value has a name matching a
+// pattern in allowlist: the nearest matching name on the path to each value decides whether it is redacted, and
+// allowlist wins if a name matches both. If allowlist is empty, values not belonging to a matching member are
+// preserved, else they are redacted, so that only the values of allowlisted members are kept.
+//
+// Patterns are matched against whole names, after their escape sequences have been decoded, ignoring case. In a
+// pattern, * matches any sequence of characters, including none, and ? matches any single character, so "*_token"
+// matches "access_token" and "Refresh_Token". An error is returned if any pattern is empty.
+func CompileKeyRedactRules(denylist []string, allowlist []string) (RedactRules, error) {
+	rules := RedactRules{selectors: make([]pathSelector, 0, len(denylist)+len(allowlist)+1)}
+	if len(allowlist) != 0 {
+		rules.selectors = append(rules.selectors, pathSelector{})
+	}
+	for _, pattern := range denylist {
+		selector, err := compileNamePattern(pattern, false)
+		if err != nil {
+			return RedactRules{}, err
+		}
+		rules.selectors = append(rules.selectors, selector)
+	}
+	for _, pattern := range allowlist {
+		selector, err := compileNamePattern(pattern, true)
+		if err != nil {
+			return RedactRules{}, err
+		}
+		rules.selectors = append(rules.selectors, selector)
+	}
+	return rules, nil
+}
+
+func compileNamePattern(pattern string, preserve bool) (pathSelector, error) {
+	if pattern == "" {
+		return pathSelector{}, fmt.Errorf("invalid name pattern %q: expected at least one character", pattern)
+	}
+	return pathSelector{
+		segments: []pathSegment{{kind: pathSegmentNamePattern, name: pattern, descendant: true}},
+		preserve: preserve,
+	}, nil
+}
+
+func compilePath(path string) ([]pathSegment, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("invalid path %q: expected $ at index 0", path)
+	}
+	var segments []pathSegment
+	index := 1
+	for index < len(path) {
+		var segment pathSegment
+		switch {
+		case strings.HasPrefix(path[index:], ".."):
+			segment.descendant = true
+			index += 2
+		case path[index] == '.':
+			index += 1
+		case path[index] == '[':
+		default:
+			return nil, fmt.Errorf("invalid path %q: expected any of .[ at index %d", path, index)
+		}
+		if index < len(path) && path[index] == '[' {
+			var err error
+			index, err = compileBracketSegment(path, index, &segment)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			nameEnd := index
+			for nameEnd < len(path) && path[nameEnd] != '.' && path[nameEnd] != '[' {
+				nameEnd += 1
+			}
+			if nameEnd == index {
+				return nil, fmt.Errorf("invalid path %q: expected a name at index %d", path, index)
+			}
+			segment.name = path[index:nameEnd]
+			if segment.name == "*" {
+				segment.kind = pathSegmentWildcard
+			}
+			index = nameEnd
+		}
+		segments = append(segments, segment)
+	}
+	return segments, nil
+}
+
+// compileBracketSegment compiles the bracketed segment starting at path[index] into segment, and returns the index
+// following it.
+func compileBracketSegment(path string, index int, segment *pathSegment) (int, error) {
+	index += 1
+	closeIndex := strings.IndexByte(path[index:], ']')
+	switch {
+	case index < len(path) && (path[index] == '\'' || path[index] == '"'):
+		quote := path[index]
+		var name strings.Builder
+		for index += 1; ; index += 1 {
+			if index == len(path) {
+				return 0, fmt.Errorf("invalid path %q: expected %c but reached end of path", path, quote)
+			}
+			if path[index] == quote {
+				break
+			}
+			if path[index] == '\\' && index+1 < len(path) {
+				index += 1
+			}
+			name.WriteByte(path[index])
+		}
+		index += 1
+		if index == len(path) || path[index] != ']' {
+			return 0, fmt.Errorf("invalid path %q: expected ] at index %d", path, index)
+		}
+		segment.name = name.String()
+		return index + 1, nil
+	case closeIndex == -1:
+		return 0, fmt.Errorf("invalid path %q: expected ] but reached end of path", path)
+	case path[index:index+closeIndex] == "*":
+		segment.kind = pathSegmentWildcard
+		return index + closeIndex + 1, nil
+	}
+	arrayIndex, err := strconv.Atoi(path[index : index+closeIndex])
+	if err != nil || arrayIndex < 0 || path[index] == '+' {
+		return 0, fmt.Errorf("invalid path %q: expected an array index at index %d", path, index)
+	}
+	segment.kind = pathSegmentIndex
+	segment.index = arrayIndex
+	return index + closeIndex + 1, nil
+}
+
+// matches reports whether segment matches a member called name if index is -1, else the element at index.
+func (segment *pathSegment) matches(name []byte, index int) bool {
+	switch segment.kind {
+	case pathSegmentName:
+		return index == -1 && segment.name == string(name)
+	case pathSegmentIndex:
+		return segment.index == index
+	case pathSegmentNamePattern:
+		return index == -1 && matchNamePattern(segment.name, name)
+	}
+	return true
+}
+
+// matchNamePattern reports whether name matches pattern, as described by CompileKeyRedactRules.
+func matchNamePattern(pattern string, name []byte) bool {
+	patternIndex, nameIndex := 0, 0
+	// After a *, the indexes to resume from if the rest of the pattern fails to match, with the * consuming one more
+	// character of name.
+	starPatternIndex, starNameIndex := -1, 0
+	for nameIndex < len(name) {
+		if patternIndex < len(pattern) {
+			switch pattern[patternIndex] {
+			case '*':
+				starPatternIndex, starNameIndex = patternIndex, nameIndex
+				patternIndex += 1
+				continue
+			case '?':
+				_, nameSize := utf8.DecodeRune(name[nameIndex:])
+				patternIndex += 1
+				nameIndex += nameSize
+				continue
+			default:
+				patternRune, patternSize := utf8.DecodeRuneInString(pattern[patternIndex:])
+				nameRune, nameSize := utf8.DecodeRune(name[nameIndex:])
+				if equalFoldRune(patternRune, nameRune) {
+					patternIndex += patternSize
+					nameIndex += nameSize
+					continue
+				}
+			}
+		}
+		if starPatternIndex == -1 {
+			return false
+		}
+		_, nameSize := utf8.DecodeRune(name[starNameIndex:])
+		starNameIndex += nameSize
+		patternIndex, nameIndex = starPatternIndex+1, starNameIndex
+	}
+	for patternIndex < len(pattern) && pattern[patternIndex] == '*' {
+		patternIndex += 1
+	}
+	return patternIndex == len(pattern)
+}
+
+// equalFoldRune reports whether a and b are equal under simple Unicode case folding, as strings.EqualFold does.
+func equalFoldRune(a rune, b rune) bool {
+	if a == b {
+		return true
+	}
+	for folded := unicode.SimpleFold(a); folded != a; folded = unicode.SimpleFold(folded) {
+		if folded == b {
+			return true
+		}
+	}
+	return false
+}
+
+// selectorState records that the value being consumed has matched the first segment segments of the selector at
+// index selector of RedactRules.selectors.
+type selectorState struct {
+	selector int
+	segment  int
+}
+
+// redactRulesMatcher tracks which selectors of rules match the value being consumed, and whether it should be
+// redacted, as a jsonRedactor descends into and returns from the members and elements of containers. The states of
+// each value on the path from the root are held one after another in states, starting at the index held at the same
+// depth of stateStarts.
+type redactRulesMatcher struct {
+	rules       *RedactRules
+	states      []selectorState
+	stateStarts []int
+	redacting   []bool
+	nameBuffer  []byte
+}
+
+func newRedactRulesMatcher(rules *RedactRules) *redactRulesMatcher {
+	matcher := &redactRulesMatcher{rules: rules}
+	for selector := range rules.selectors {
+		matcher.states = append(matcher.states, selectorState{selector: selector})
+	}
+	matcher.push(0, false)
+	return matcher
+}
+
+// enterMember moves the matcher from an object to its member called rawName, where rawName is the name between its
+// quotes, still escaped.
+func (matcher *redactRulesMatcher) enterMember(rawName []byte) {
+	parentStart := matcher.stateStarts[len(matcher.stateStarts)-1]
+	var name []byte
+	if parentStart != len(matcher.states) {
+		matcher.nameBuffer = appendUnescaped(matcher.nameBuffer[:0], rawName)
+		name = matcher.nameBuffer
+	}
+	matcher.enter(name, -1)
+}
+
+// enterElement moves the matcher from an array to its element at index.
+func (matcher *redactRulesMatcher) enterElement(index int) {
+	matcher.enter(nil, index)
+}
+
+// enter moves the matcher to a member called name if index is -1, else to the element at index.
+func (matcher *redactRulesMatcher) enter(name []byte, index int) {
+	parentStart := matcher.stateStarts[len(matcher.stateStarts)-1]
+	parentEnd := len(matcher.states)
+	for _, state := range matcher.states[parentStart:parentEnd] {
+		segments := matcher.rules.selectors[state.selector].segments
+		if state.segment == len(segments) {
+			continue
+		}
+		segment := &segments[state.segment]
+		if segment.descendant {
+			matcher.addState(parentEnd, state)
+		}
+		if segment.matches(name, index) {
+			matcher.addState(parentEnd, selectorState{selector: state.selector, segment: state.segment + 1})
+		}
+	}
+	matcher.push(parentEnd, matcher.redacting[len(matcher.redacting)-1])
+}
+
+// addState adds state to the states starting at start, unless it is already among them.
+func (matcher *redactRulesMatcher) addState(start int, state selectorState) {
+	for _, existingState := range matcher.states[start:] {
+		if existingState == state {
+			return
+		}
+	}
+	matcher.states = append(matcher.states, state)
+}
+
+// push begins the states of a value at start, and decides whether it is redacted from the selectors fully matched by
+// them, or else inherits redacting from its container.
+func (matcher *redactRulesMatcher) push(start int, redacting bool) {
+	matchedRedact, matchedPreserve := false, false
+	for _, state := range matcher.states[start:] {
+		selector := &matcher.rules.selectors[state.selector]
+		if state.segment == len(selector.segments) {
+			if selector.preserve {
+				matchedPreserve = true
+			} else {
+				matchedRedact = true
+			}
+		}
+	}
+	if matchedPreserve {
+		redacting = false
+	} else if matchedRedact {
+		redacting = true
+	}
+	matcher.stateStarts = append(matcher.stateStarts, start)
+	matcher.redacting = append(matcher.redacting, redacting)
+}
+
+// leave moves the matcher from a member or element back to its container.
+func (matcher *redactRulesMatcher) leave() {
+	depth := len(matcher.stateStarts) - 1
+	matcher.states = matcher.states[:matcher.stateStarts[depth]]
+	matcher.stateStarts = matcher.stateStarts[:depth]
+	matcher.redacting = matcher.redacting[:depth]
+}
+
+func (matcher *redactRulesMatcher) isRedacting() bool {
+	return matcher.redacting[len(matcher.redacting)-1]
+}
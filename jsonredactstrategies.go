@@ -0,0 +1,278 @@
+package jsonbytes
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+)
+
+// Strategy decides what a Redactor replaces a redacted value with.
+type Strategy interface {
+	// AppendRedacted appends the replacement for raw, a redacted value exactly as it appeared in the JSON, including
+	// the quotes of a string, to dst and returns the extended buffer. The replacement must be a valid JSON value.
+	AppendRedacted(dst []byte, raw []byte) []byte
+}
+
+// literalStrategy replaces every value with the same JSON value.
+type literalStrategy string
+
+func (strategy literalStrategy) AppendRedacted(dst []byte, raw []byte) []byte {
+	return append(dst, strategy...)
+}
+
+type keepStrategy struct{}
+
+func (keepStrategy) AppendRedacted(dst []byte, raw []byte) []byte {
+	return append(dst, raw...)
+}
+
+var (
+	// Keep replaces a value with itself, so that it is left unredacted. It is the default for nulls.
+	Keep Strategy = keepStrategy{}
+	// EmptyString replaces a value with "". It is the default for strings.
+	EmptyString Strategy = literalStrategy(`""`)
+	// ZeroNumber replaces a value with 0. It is the default for numbers.
+	ZeroNumber Strategy = literalStrategy("0")
+	// TrueBoolean replaces a value with true. It is the default for booleans.
+	TrueBoolean Strategy = literalStrategy("true")
+	// NumberMagnitude replaces a number with the power of ten with the same sign and magnitude, so 1234.5 becomes
+	// 1000, -0.05 becomes -0.01 and 6.02e23 becomes 1e23, while 0 stays 0. Other values are replaced with 0.
+	NumberMagnitude Strategy = numberMagnitudeStrategy{}
+)
+
+// Marker returns a Strategy which replaces a value with the string marker, such as "[REDACTED]".
+func Marker(marker string) Strategy {
+	return literalStrategy(append(appendEscaped([]byte{'"'}, []byte(marker)), '"'))
+}
+
+// MaskString returns a Strategy which replaces a value with a string of the same number of characters, each of which
+// is mask, so "hunter2" becomes "*******" if mask is '*'. The characters of a string are counted after its escape
+// sequences have been decoded, and those of any other value are the bytes of its JSON, so a number keeps its number of
+// digits.
+func MaskString(mask rune) Strategy {
+	return maskStrategy{mask: appendEscaped(nil, utf8.AppendRune(nil, mask))}
+}
+
+// KeepEnds returns a Strategy which is the same as MaskString('*'), except that the first first and last last
+// characters are kept, so "4111111111111111" becomes "4111********1111" if first and last are 4. If a value has no
+// more than first+last characters, all of them are masked, so that short values are not revealed in full.
+func KeepEnds(first int, last int) Strategy {
+	return maskStrategy{mask: []byte{'*'}, first: max(first, 0), last: max(last, 0)}
+}
+
+type maskStrategy struct {
+	// mask is already escaped.
+	mask  []byte
+	first int
+	last  int
+}
+
+func (strategy maskStrategy) AppendRedacted(dst []byte, raw []byte) []byte {
+	characters := raw
+	if raw[0] == '"' {
+		var buffer [64]byte
+		characters = appendUnescaped(buffer[:0], raw[1:len(raw)-1])
+	}
+	count := utf8.RuneCount(characters)
+	first, last := strategy.first, strategy.last
+	if first+last >= count {
+		first, last = 0, 0
+	}
+	dst = append(dst, '"')
+	index := 0
+	for character := 0; character < count; character++ {
+		_, size := utf8.DecodeRune(characters[index:])
+		if character < first || character >= count-last {
+			dst = appendEscaped(dst, characters[index:index+size])
+		} else {
+			dst = append(dst, strategy.mask...)
+		}
+		index += size
+	}
+	return append(dst, '"')
+}
+
+type numberMagnitudeStrategy struct{}
+
+func (numberMagnitudeStrategy) AppendRedacted(dst []byte, raw []byte) []byte {
+	negative := raw[0] == '-'
+	if negative {
+		raw = raw[1:]
+	}
+	if len(raw) == 0 || raw[0] < '0' || raw[0] > '9' {
+		return append(dst, '0')
+	}
+	// The exponent of the power of ten is found from the position of the first non-zero digit relative to the decimal
+	// point, plus the exponent part, so that numbers of any length are handled without parsing them.
+	index := 0
+	for index < len(raw) && '0' <= raw[index] && raw[index] <= '9' {
+		index += 1
+	}
+	exponent, isZero := index-1, raw[0] == '0'
+	if index < len(raw) && raw[index] == '.' {
+		index += 1
+		for ; index < len(raw) && '0' <= raw[index] && raw[index] <= '9'; index++ {
+			if isZero {
+				exponent -= 1
+				isZero = raw[index] == '0'
+			}
+		}
+	}
+	if isZero {
+		return append(dst, '0')
+	}
+	if index < len(raw) && (raw[index] == 'e' || raw[index] == 'E') {
+		index += 1
+		exponentNegative := raw[index] == '-'
+		if raw[index] == '-' || raw[index] == '+' {
+			index += 1
+		}
+		exponentPart := 0
+		for ; index < len(raw); index++ {
+			// Saturate rather than overflow on absurdly large exponents.
+			exponentPart = min(exponentPart*10+int(raw[index]-'0'), 100000000)
+		}
+		if exponentNegative {
+			exponentPart = -exponentPart
+		}
+		exponent += exponentPart
+	}
+	if negative {
+		dst = append(dst, '-')
+	}
+	switch {
+	case 0 <= exponent && exponent <= 20:
+		dst = append(dst, '1')
+		for ; exponent > 0; exponent-- {
+			dst = append(dst, '0')
+		}
+	case -6 <= exponent && exponent < 0:
+		dst = append(dst, '0', '.')
+		for ; exponent < -1; exponent++ {
+			dst = append(dst, '0')
+		}
+		dst = append(dst, '1')
+	default:
+		dst = append(dst, '1', 'e')
+		dst = strconv.AppendInt(dst, int64(exponent), 10)
+	}
+	return dst
+}
+
+// HMACTokenSize is the number of bytes of the HMAC-SHA256 of a value which the Strategy returned by HMAC keeps.
+const HMACTokenSize = 16
+
+// HMAC returns a Strategy which replaces a value with a token of the form "h:3fa9c1...", where the hex digits are the
+// first HMACTokenSize bytes of the HMAC-SHA256 of the value keyed with key. Equal values are replaced with equal tokens
+// for as long as the same key is used, so redacted values can be correlated, such as the same email address across
+// many log lines, without revealing them to anyone who does not hold key. A string is hashed after its escape
+// sequences have been decoded, so "\u0061" and "a" have the same token, while any other value is hashed as its JSON,
+// so the number 1 has the same token as the string "1", but not 1.0. key should be a secret of at least 32 random
+// bytes, as short or guessable values can be recovered from their tokens by hashing every candidate.
+func HMAC(key []byte) Strategy {
+	key = append([]byte(nil), key...)
+	return &hmacStrategy{
+		hashes: sync.Pool{New: func() any {
+			return hmac.New(sha256.New, key)
+		}},
+	}
+}
+
+type hmacStrategy struct {
+	// hashes holds hash.Hashes keyed with the key, as they are expensive to create but cannot be used concurrently.
+	hashes sync.Pool
+}
+
+func (strategy *hmacStrategy) AppendRedacted(dst []byte, raw []byte) []byte {
+	value := raw
+	if raw[0] == '"' {
+		var buffer [64]byte
+		value = appendUnescaped(buffer[:0], raw[1:len(raw)-1])
+	}
+	hmacHash := strategy.hashes.Get().(hash.Hash)
+	hmacHash.Reset()
+	hmacHash.Write(value)
+	var sum [sha256.Size]byte
+	hmacHash.Sum(sum[:0])
+	strategy.hashes.Put(hmacHash)
+	dst = append(dst, '"', 'h', ':')
+	dst = hex.AppendEncode(dst, sum[:HMACTokenSize])
+	return append(dst, '"')
+}
+
+// Redactor redacts the values of JSON, like Redact, but with strategies to control what the values of each type are
+// replaced with, so that readers of the redacted JSON can still tell what shape the original values had. The zero
+// value redacts every value in the same manner as RedactAllValues.
+type Redactor struct {
+	// Rules selects which values are redacted. If it is nil, every value is.
+	Rules *RedactRules
+	// Strings, Numbers, Booleans and Nulls are the strategies used for redacted values of each type. Any which are nil
+	// default to EmptyString, ZeroNumber, TrueBoolean and Keep respectively.
+	Strings  Strategy
+	Numbers  Strategy
+	Booleans Strategy
+	Nulls    Strategy
+	// PreserveWhitespace keeps the whitespace between tokens exactly as it was, rather than removing it, so that
+	// redacted JSON which was laid out for people to read, such as a configuration file, lines up line for line with
+	// the original, and only the values which were redacted differ.
+	PreserveWhitespace bool
+	// Options configures the checks made on the JSON.
+	Options Options
+}
+
+// ErrBuffersOverlap is returned by Redactor.Redact when dst is src[:0] and its strategies could replace values with
+// longer ones, which would overwrite the parts of src which are yet to be read.
+var ErrBuffersOverlap = errors.New("dst overlaps src")
+
+// Redact appends src to dst with its values redacted as configured by redactor and, unless PreserveWhitespace is set,
+// unecessary whitespace characters removed, and returns the extended buffer. dst may be src[:0] to redact src in place
+// if each strategy is nil or Keep, as the values they replace are never lengthened, and otherwise ErrBuffersOverlap is
+// returned. dst must not overlap src in any other way. If src is not a valid JSON value, dst is returned unextended
+// along with a *SyntaxError explaining why.
+func (redactor Redactor) Redact(dst []byte, src []byte) ([]byte, error) {
+	jsonRedactor, err := newJsonRedactor(src, dst, redactor.Options)
+	if err != nil {
+		return dst, err
+	}
+	if jsonRedactor.inPlace && !redactor.neverLengthens() {
+		return dst, ErrBuffersOverlap
+	}
+	if redactor.Strings == nil {
+		redactor.Strings = EmptyString
+	}
+	if redactor.Numbers == nil {
+		redactor.Numbers = ZeroNumber
+	}
+	if redactor.Booleans == nil {
+		redactor.Booleans = TrueBoolean
+	}
+	if redactor.Nulls == nil {
+		redactor.Nulls = Keep
+	}
+	jsonRedactor.strategies = &redactor
+	jsonRedactor.preserveWhitespace = redactor.PreserveWhitespace
+	if redactor.Rules != nil {
+		jsonRedactor.matcher = newRedactRulesMatcher(redactor.Rules)
+	}
+	redactedJson, err := jsonRedactor.redact()
+	if err != nil {
+		return dst, err
+	}
+	return redactedJson, nil
+}
+
+// neverLengthens reports whether the strategies of redactor, before the defaults are filled in, never replace a value
+// with a longer one.
+func (redactor Redactor) neverLengthens() bool {
+	for _, strategy := range []Strategy{redactor.Strings, redactor.Numbers, redactor.Booleans, redactor.Nulls} {
+		if strategy != nil && strategy != Keep {
+			return false
+		}
+	}
+	return true
+}
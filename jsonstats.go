@@ -0,0 +1,208 @@
+package jsonbytes
+
+// DocumentStats describes the complexity of a JSON value, as returned by Stats.
+type DocumentStats struct {
+	// Objects, Arrays, Strings, Numbers, Booleans and Nulls are the number of values of each kind, at any depth, where
+	// the names of the members of objects are not counted as strings.
+	Objects  int
+	Arrays   int
+	Strings  int
+	Numbers  int
+	Booleans int
+	Nulls    int
+	// MaxDepth is the depth of the most deeply nested object or array, counting from one for the outermost, or zero if
+	// there are none.
+	MaxDepth int
+	// LongestString is the length in bytes of the longest string, as it appears in the JSON, excluding its quotes, so
+	// escape sequences count with their full length. Names are not counted.
+	LongestString int
+	// Bytes is the length of the whole JSON. KeyBytes is the number of bytes of the names of the members of objects,
+	// including their quotes, ValueBytes is the number of bytes of strings, numbers, booleans and nulls, and
+	// WhitespaceBytes is the number of bytes of whitespace between them. The rest are braces, brackets, colons and
+	// commas.
+	Bytes           int
+	KeyBytes        int
+	ValueBytes      int
+	WhitespaceBytes int
+}
+
+// WhitespaceRatio returns the fraction of the bytes of the JSON which are whitespace, which is zero for compact JSON.
+func (stats DocumentStats) WhitespaceRatio() float64 {
+	if stats.Bytes == 0 {
+		return 0
+	}
+	return float64(stats.WhitespaceBytes) / float64(stats.Bytes)
+}
+
+// jsonStatsCollector counts the parts of json in stats as it is consumed.
+type jsonStatsCollector struct {
+	// jsonValidator is held by value for the same reason as in jsonRedactor.
+	jsonValidator jsonValidator
+	stats         DocumentStats
+	// structuralBytes is the number of braces, brackets, colons and commas consumed, from which the number of bytes of
+	// whitespace is found once everything else has been counted.
+	structuralBytes int
+}
+
+// collect consumes the whole of json, and returns nil if it was a valid JSON value.
+func (state *jsonStatsCollector) collect() error {
+	err := state.consumeValue()
+	if err != nil {
+		return err
+	}
+	if state.jsonValidator.readIndex != state.jsonValidator.jsonLength {
+		return state.jsonValidator.errorTrailingData()
+	}
+	stats := &state.stats
+	stats.Bytes = state.jsonValidator.jsonLength
+	stats.WhitespaceBytes = stats.Bytes - stats.KeyBytes - stats.ValueBytes - state.structuralBytes
+	return nil
+}
+
+func (state *jsonStatsCollector) consumeValue() error {
+	state.jsonValidator.consumeWhitespace()
+	if state.jsonValidator.readIndex == state.jsonValidator.jsonLength {
+		return state.jsonValidator.errorUnexpectedEnd()
+	}
+	valueStart := state.jsonValidator.readIndex
+	var err error
+	switch state.jsonValidator.readHead {
+	case '"':
+		err = state.jsonValidator.consumeString()
+		state.stats.Strings += 1
+		state.stats.LongestString = max(state.stats.LongestString, state.jsonValidator.readIndex-valueStart-2)
+	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		err = state.jsonValidator.consumeNumber()
+		state.stats.Numbers += 1
+	case '{':
+		return state.consumeObject()
+	case '[':
+		return state.consumeArray()
+	case 't':
+		err = state.jsonValidator.consumeTrue()
+		state.stats.Booleans += 1
+	case 'f':
+		err = state.jsonValidator.consumeFalse()
+		state.stats.Booleans += 1
+	case 'n':
+		err = state.jsonValidator.consumeNull()
+		state.stats.Nulls += 1
+	default:
+		return state.jsonValidator.errorUnexpectedCharacter("any of \"10123456789{[tfn")
+	}
+	if err != nil {
+		return err
+	}
+	state.stats.ValueBytes += state.jsonValidator.readIndex - valueStart
+	state.jsonValidator.consumeWhitespace()
+	return nil
+}
+
+func (state *jsonStatsCollector) consumeObject() error {
+	err := state.enterContainer()
+	if err != nil {
+		return err
+	}
+	state.stats.Objects += 1
+	state.jsonValidator.consumeWhitespace()
+	if state.jsonValidator.readIndex == state.jsonValidator.jsonLength {
+		return state.jsonValidator.errorUnexpectedEnd()
+	}
+	if state.jsonValidator.readHead == '}' {
+		return state.consumeContainerEnd('}')
+	}
+	for {
+		nameStart := state.jsonValidator.readIndex
+		err = state.jsonValidator.consumeName()
+		if err != nil {
+			return err
+		}
+		state.stats.KeyBytes += state.jsonValidator.readIndex - nameStart
+		state.jsonValidator.consumeWhitespace()
+		if state.jsonValidator.readIndex == state.jsonValidator.jsonLength {
+			return state.jsonValidator.errorUnexpectedEnd()
+		}
+		err = state.jsonValidator.consumeByte(':')
+		if err != nil {
+			return err
+		}
+		state.structuralBytes += 1
+		err = state.consumeValue()
+		if err != nil {
+			return err
+		}
+		if state.jsonValidator.readIndex == state.jsonValidator.jsonLength {
+			return state.jsonValidator.errorUnexpectedEnd()
+		}
+		switch state.jsonValidator.readHead {
+		case ',':
+			state.jsonValidator.readUnsafe()
+			state.structuralBytes += 1
+			state.jsonValidator.consumeWhitespace()
+			if state.jsonValidator.readIndex == state.jsonValidator.jsonLength {
+				return state.jsonValidator.errorUnexpectedEnd()
+			}
+		case '}':
+			return state.consumeContainerEnd('}')
+		default:
+			return state.jsonValidator.errorUnexpectedCharacter("any of ,}")
+		}
+	}
+}
+
+func (state *jsonStatsCollector) consumeArray() error {
+	err := state.enterContainer()
+	if err != nil {
+		return err
+	}
+	state.stats.Arrays += 1
+	state.jsonValidator.consumeWhitespace()
+	if state.jsonValidator.readIndex == state.jsonValidator.jsonLength {
+		return state.jsonValidator.errorUnexpectedEnd()
+	}
+	if state.jsonValidator.readHead == ']' {
+		return state.consumeContainerEnd(']')
+	}
+	for {
+		err = state.consumeValue()
+		if err != nil {
+			return err
+		}
+		if state.jsonValidator.readIndex == state.jsonValidator.jsonLength {
+			return state.jsonValidator.errorUnexpectedEnd()
+		}
+		switch state.jsonValidator.readHead {
+		case ',':
+			state.jsonValidator.readUnsafe()
+			state.structuralBytes += 1
+		case ']':
+			return state.consumeContainerEnd(']')
+		default:
+			return state.jsonValidator.errorUnexpectedCharacter("any of ,]")
+		}
+	}
+}
+
+// enterContainer consumes the opening byte of an object or array, having checked it is not nested too deeply.
+func (state *jsonStatsCollector) enterContainer() error {
+	err := state.jsonValidator.enterContainer()
+	if err != nil {
+		return err
+	}
+	state.stats.MaxDepth = max(state.stats.MaxDepth, state.jsonValidator.depth)
+	state.jsonValidator.readUnsafe()
+	state.structuralBytes += 1
+	return nil
+}
+
+// consumeContainerEnd consumes the closing byte of an object or array, and the whitespace following it.
+func (state *jsonStatsCollector) consumeContainerEnd(closingByte byte) error {
+	state.jsonValidator.depth -= 1
+	err := state.jsonValidator.consumeByte(closingByte)
+	if err != nil {
+		return err
+	}
+	state.structuralBytes += 1
+	state.jsonValidator.consumeWhitespace()
+	return nil
+}
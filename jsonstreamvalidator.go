@@ -0,0 +1,63 @@
+package jsonbytes
+
+import "io"
+
+// Validator is a push-style alternative to ValidateReader, for when a JSON value arrives in chunks that are handed to
+// you rather than read by you, such as the body of a request being proxied. Each chunk is passed to a goroutine which
+// validates it with ValidateReader, so a Validator never holds more than a small fixed size buffer of the value in
+// memory.
+//
+// The chunks are validated in the background, so the error in an invalid chunk is usually not returned by the Write
+// which wrote it, but by a later Write or by Close. Only the result of Close is final. Close must always be called,
+// even after Write has returned an error or if the rest of the value is abandoned, as otherwise the goroutine is
+// leaked.
+type Validator struct {
+	pipeWriter *io.PipeWriter
+	result     chan error
+	err        error
+}
+
+// NewValidator returns a new Validator, ready to be written to.
+func NewValidator() *Validator {
+	return Options{}.NewValidator()
+}
+
+// NewValidator is the same as the package level NewValidator, but the Validator returned also makes the checks
+// enabled by options.
+func (options Options) NewValidator() *Validator {
+	pipeReader, pipeWriter := io.Pipe()
+	validator := &Validator{
+		pipeWriter: pipeWriter,
+		result:     make(chan error, 1),
+	}
+	go func() {
+		err := options.ValidateReader(pipeReader)
+		if err != nil {
+			pipeReader.CloseWithError(err)
+		} else {
+			pipeReader.Close()
+		}
+		validator.result <- err
+	}()
+	return validator
+}
+
+// Write passes the next chunk of the JSON value to be validated, and returns once all of it has been taken, which may
+// be before it has been validated. If the bytes written before it are already known not to be the start of a valid
+// JSON value, Write returns the same error that Close will, but an error in p itself may not be returned until the
+// next Write or Close.
+func (validator *Validator) Write(p []byte) (int, error) {
+	return validator.pipeWriter.Write(p)
+}
+
+// Close signals that all of the JSON value has been written, and returns nil if it was a valid JSON value, else an
+// error detailing why it was not. The error returned is always a *SyntaxError, unless it is a *DuplicateNameError.
+// Calling Close more than once returns the same result each time.
+func (validator *Validator) Close() error {
+	if validator.result != nil {
+		validator.pipeWriter.Close()
+		validator.err = <-validator.result
+		validator.result = nil
+	}
+	return validator.err
+}
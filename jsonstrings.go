@@ -0,0 +1,114 @@
+package jsonbytes
+
+import (
+	"bytes"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// appendUnescaped appends the string encoded by raw, the bytes of a valid JSON string or name between its quotes, to
+// dst with its escape sequences decoded, and returns the extended buffer.
+func appendUnescaped(dst []byte, raw []byte) []byte {
+	for {
+		escapeIndex := bytes.IndexByte(raw, '\\')
+		if escapeIndex == -1 {
+			return append(dst, raw...)
+		}
+		dst = append(dst, raw[:escapeIndex]...)
+		decoded, escapeLength := decodeEscape(raw[escapeIndex:])
+		dst = utf8.AppendRune(dst, decoded)
+		raw = raw[escapeIndex+escapeLength:]
+	}
+}
+
+// equalUnescaped reports whether raw, the bytes of a valid JSON string or name between its quotes, encodes s, without
+// allocating.
+func equalUnescaped(raw []byte, s string) bool {
+	for {
+		escapeIndex := bytes.IndexByte(raw, '\\')
+		if escapeIndex == -1 {
+			return string(raw) == s
+		}
+		if len(s) < escapeIndex || string(raw[:escapeIndex]) != s[:escapeIndex] {
+			return false
+		}
+		s = s[escapeIndex:]
+		decoded, escapeLength := decodeEscape(raw[escapeIndex:])
+		var encoded [utf8.UTFMax]byte
+		encodedLength := utf8.EncodeRune(encoded[:], decoded)
+		if len(s) < encodedLength || string(encoded[:encodedLength]) != s[:encodedLength] {
+			return false
+		}
+		s = s[encodedLength:]
+		raw = raw[escapeIndex+escapeLength:]
+	}
+}
+
+// decodeEscape decodes the valid escape sequence at the start of raw, including a following \u escape if the two form
+// a UTF-16 surrogate pair, and returns the rune it encodes along with its length. \u escapes of unpaired surrogates are
+// decoded as utf8.RuneError, as encoding/json does.
+func decodeEscape(raw []byte) (rune, int) {
+	switch raw[1] {
+	case 'b':
+		return '\b', 2
+	case 'f':
+		return '\f', 2
+	case 'n':
+		return '\n', 2
+	case 'r':
+		return '\r', 2
+	case 't':
+		return '\t', 2
+	case 'u':
+		codeUnit := decodeHex4(raw[2:6])
+		if !utf16.IsSurrogate(codeUnit) {
+			return codeUnit, 6
+		}
+		if len(raw) >= 12 && raw[6] == '\\' && raw[7] == 'u' {
+			if decoded := utf16.DecodeRune(codeUnit, decodeHex4(raw[8:12])); decoded != utf8.RuneError {
+				return decoded, 12
+			}
+		}
+		return utf8.RuneError, 6
+	}
+	return rune(raw[1]), 2
+}
+
+// decodeHex4 decodes the four hex digits of a \u escape, which must already have been validated.
+func decodeHex4(hex []byte) rune {
+	var decoded rune
+	for _, digit := range hex[:4] {
+		switch {
+		case digit <= '9':
+			decoded = decoded<<4 | rune(digit-'0')
+		case digit <= 'F':
+			decoded = decoded<<4 | rune(digit-'A'+10)
+		default:
+			decoded = decoded<<4 | rune(digit-'a'+10)
+		}
+	}
+	return decoded
+}
+
+// appendEscaped appends s to dst escaped as the contents of a JSON string, escaping only the bytes rfc8259 requires to
+// be escaped, and returns the extended buffer.
+func appendEscaped(dst []byte, s []byte) []byte {
+	const hexDigits = "0123456789abcdef"
+	for _, character := range s {
+		switch {
+		case character == '"' || character == '\\':
+			dst = append(dst, '\\', character)
+		case character == '\n':
+			dst = append(dst, '\\', 'n')
+		case character == '\r':
+			dst = append(dst, '\\', 'r')
+		case character == '\t':
+			dst = append(dst, '\\', 't')
+		case character < 0x20:
+			dst = append(dst, '\\', 'u', '0', '0', hexDigits[character>>4], hexDigits[character&0xf])
+		default:
+			dst = append(dst, character)
+		}
+	}
+	return dst
+}
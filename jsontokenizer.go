@@ -0,0 +1,273 @@
+package jsonbytes
+
+import (
+	"fmt"
+	"io"
+)
+
+// TokenKind is the type of a Token.
+type TokenKind int
+
+const (
+	// TokenInvalid is returned along with an error, when there is no token.
+	TokenInvalid TokenKind = iota
+	TokenObjectStart
+	TokenObjectEnd
+	TokenArrayStart
+	TokenArrayEnd
+	// TokenName is the name of a member of an object. The colon following it is not part of the token.
+	TokenName
+	TokenString
+	TokenNumber
+	TokenTrue
+	TokenFalse
+	TokenNull
+)
+
+func (kind TokenKind) String() string {
+	switch kind {
+	case TokenInvalid:
+		return "invalid"
+	case TokenObjectStart:
+		return "object start"
+	case TokenObjectEnd:
+		return "object end"
+	case TokenArrayStart:
+		return "array start"
+	case TokenArrayEnd:
+		return "array end"
+	case TokenName:
+		return "name"
+	case TokenString:
+		return "string"
+	case TokenNumber:
+		return "number"
+	case TokenTrue:
+		return "true"
+	case TokenFalse:
+		return "false"
+	case TokenNull:
+		return "null"
+	}
+	return fmt.Sprintf("TokenKind(%d)", int(kind))
+}
+
+// Token is a single token of a JSON value. Raw is the bytes of the token exactly as they appear in the JSON, including
+// the quotes and escape sequences of names and strings, and is a subslice of it rather than a copy.
+type Token struct {
+	Kind TokenKind
+	Raw  []byte
+}
+
+// tokenizerExpectation is what a Tokenizer will accept next, ignoring whitespace.
+type tokenizerExpectation int
+
+const (
+	expectValue tokenizerExpectation = iota
+	// expectValueOrEnd follows the opening byte of an array, which may be closed straight away.
+	expectValueOrEnd
+	expectName
+	// expectNameOrEnd follows the opening byte of an object, which may be closed straight away.
+	expectNameOrEnd
+	expectColon
+	// expectCommaOrEnd follows a value in an object or array.
+	expectCommaOrEnd
+	// expectEndOfJson follows the whole JSON value, after which only whitespace may remain.
+	expectEndOfJson
+)
+
+// Tokenizer splits a JSON value into its tokens, validating it as it goes, so that transforms can be built on top of
+// the same validation as the rest of this package. Tokens are read one at a time with Next, without allocating.
+type Tokenizer struct {
+	// jsonValidator is held by value for the same reason as in jsonRedactor.
+	jsonValidator jsonValidator
+	expect        tokenizerExpectation
+	// closingBytes holds the closing byte of each object and array that has been entered but not yet closed, innermost
+	// last. It starts out backed by closingBytesBuffer, so that only very deeply nested JSON causes it to allocate.
+	closingBytes       []byte
+	closingBytesBuffer [64]byte
+	err                error
+}
+
+// NewTokenizer returns a new Tokenizer which reads the tokens of json. json must not be modified until the Tokenizer
+// is finished with, as the tokens it returns are subslices of it.
+func NewTokenizer(json []byte) *Tokenizer {
+	return Options{}.NewTokenizer(json)
+}
+
+// NewTokenizer is the same as the package level NewTokenizer, but the Tokenizer returned also makes the checks enabled
+// by options.
+func (options Options) NewTokenizer(json []byte) *Tokenizer {
+	tokenizer := &Tokenizer{}
+	tokenizer.closingBytes = tokenizer.closingBytesBuffer[:0]
+	jsonValidator, err := newJsonValidator(json, options)
+	if err != nil {
+		tokenizer.err = err
+		return tokenizer
+	}
+	tokenizer.jsonValidator = *jsonValidator
+	return tokenizer
+}
+
+// Next returns the next token of the JSON value. Once every token has been returned, and the JSON has been found to
+// be a valid JSON value, Next returns io.EOF. If the JSON is found not to be valid, Next returns a *SyntaxError
+// instead, having already returned every token before the one the error was found in. Once Next has returned an
+// error, it returns the same error on every call.
+func (tokenizer *Tokenizer) Next() (Token, error) {
+	if tokenizer.err != nil {
+		return Token{}, tokenizer.err
+	}
+	token, err := tokenizer.next()
+	if err != nil {
+		tokenizer.err = err
+		return Token{}, err
+	}
+	return token, nil
+}
+
+// Offset returns the index in the JSON of the byte following the last token returned by Next.
+func (tokenizer *Tokenizer) Offset() int {
+	return tokenizer.jsonValidator.readIndex
+}
+
+// Depth returns the number of objects and arrays that have been started but not yet ended by the tokens returned by
+// Next.
+func (tokenizer *Tokenizer) Depth() int {
+	return len(tokenizer.closingBytes)
+}
+
+func (tokenizer *Tokenizer) next() (Token, error) {
+	state := &tokenizer.jsonValidator
+	for {
+		state.consumeWhitespace()
+		if tokenizer.expect == expectEndOfJson {
+			if state.readIndex != state.jsonLength {
+				return Token{}, state.errorTrailingData()
+			}
+			return Token{}, io.EOF
+		}
+		if state.readIndex == state.jsonLength {
+			return Token{}, state.errorUnexpectedEnd()
+		}
+		switch tokenizer.expect {
+		case expectValueOrEnd:
+			if state.readHead == ']' {
+				return tokenizer.consumeContainerEnd(TokenArrayEnd)
+			}
+			return tokenizer.consumeValue()
+		case expectValue:
+			return tokenizer.consumeValue()
+		case expectNameOrEnd:
+			if state.readHead == '}' {
+				return tokenizer.consumeContainerEnd(TokenObjectEnd)
+			}
+			return tokenizer.consumeName()
+		case expectName:
+			return tokenizer.consumeName()
+		case expectColon:
+			err := state.consumeByte(':')
+			if err != nil {
+				return Token{}, err
+			}
+			tokenizer.expect = expectValue
+		case expectCommaOrEnd:
+			closingByte := tokenizer.closingBytes[len(tokenizer.closingBytes)-1]
+			switch state.readHead {
+			case ',':
+				state.readUnsafe()
+				if closingByte == '}' {
+					tokenizer.expect = expectName
+				} else {
+					tokenizer.expect = expectValue
+				}
+			case '}', ']':
+				if state.readHead != closingByte {
+					return Token{}, state.errorUnexpectedCharacter("any of ," + string(closingByte))
+				}
+				if closingByte == '}' {
+					return tokenizer.consumeContainerEnd(TokenObjectEnd)
+				}
+				return tokenizer.consumeContainerEnd(TokenArrayEnd)
+			default:
+				return Token{}, state.errorUnexpectedCharacter("any of ," + string(closingByte))
+			}
+		}
+	}
+}
+
+func (tokenizer *Tokenizer) consumeValue() (Token, error) {
+	state := &tokenizer.jsonValidator
+	valueStart := state.readIndex
+	var kind TokenKind
+	var err error
+	switch state.readHead {
+	case '{', '[':
+		err = state.enterContainer()
+		if err != nil {
+			return Token{}, err
+		}
+		if state.readHead == '{' {
+			kind = TokenObjectStart
+			tokenizer.closingBytes = append(tokenizer.closingBytes, '}')
+			tokenizer.expect = expectNameOrEnd
+		} else {
+			kind = TokenArrayStart
+			tokenizer.closingBytes = append(tokenizer.closingBytes, ']')
+			tokenizer.expect = expectValueOrEnd
+		}
+		state.readUnsafe()
+		return Token{Kind: kind, Raw: state.json[valueStart:state.readIndex]}, nil
+	case '"':
+		kind = TokenString
+		err = state.consumeString()
+	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		kind = TokenNumber
+		err = state.consumeNumber()
+	case 't':
+		kind = TokenTrue
+		err = state.consumeTrue()
+	case 'f':
+		kind = TokenFalse
+		err = state.consumeFalse()
+	case 'n':
+		kind = TokenNull
+		err = state.consumeNull()
+	default:
+		return Token{}, state.errorUnexpectedCharacter("any of \"10123456789{[tfn")
+	}
+	if err != nil {
+		return Token{}, err
+	}
+	tokenizer.expectAfterValue()
+	return Token{Kind: kind, Raw: state.json[valueStart:state.readIndex]}, nil
+}
+
+func (tokenizer *Tokenizer) consumeName() (Token, error) {
+	state := &tokenizer.jsonValidator
+	nameStart := state.readIndex
+	err := state.consumeName()
+	if err != nil {
+		return Token{}, err
+	}
+	tokenizer.expect = expectColon
+	return Token{Kind: TokenName, Raw: state.json[nameStart:state.readIndex]}, nil
+}
+
+// consumeContainerEnd consumes the closing byte of the innermost object or array, which must be at the read head.
+func (tokenizer *Tokenizer) consumeContainerEnd(kind TokenKind) (Token, error) {
+	state := &tokenizer.jsonValidator
+	closingStart := state.readIndex
+	state.depth -= 1
+	state.readUnsafe()
+	tokenizer.closingBytes = tokenizer.closingBytes[:len(tokenizer.closingBytes)-1]
+	tokenizer.expectAfterValue()
+	return Token{Kind: kind, Raw: state.json[closingStart:state.readIndex]}, nil
+}
+
+func (tokenizer *Tokenizer) expectAfterValue() {
+	if len(tokenizer.closingBytes) == 0 {
+		tokenizer.expect = expectEndOfJson
+	} else {
+		tokenizer.expect = expectCommaOrEnd
+	}
+}
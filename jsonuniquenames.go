@@ -0,0 +1,225 @@
+package jsonbytes
+
+import (
+	"bytes"
+	"unicode/utf8"
+)
+
+// maxComparedNames is the number of names an object can have before its names are held in a map, rather than each
+// new name being compared with every other.
+const maxComparedNames = 16
+
+// nameSpan is a name of a member of an object being consumed, held for Options.DisallowDuplicateNames.
+type nameSpan struct {
+	// depth is the depth of the object the name is in.
+	depth int
+	// offset is the offset of the opening quote of the name in the whole JSON.
+	offset int
+	// start and end are the span of the name, including its quotes, in json, or in uniqueNames.buffer if
+	// uniqueNames.copyNames is set.
+	start int
+	end   int
+}
+
+// nameIndex holds the decoded names of an object with more than maxComparedNames names, mapped to their offsets.
+type nameIndex struct {
+	depth int
+	names map[string]int
+}
+
+// uniqueNames holds the names of the members of the objects being consumed, so that a jsonValidator can detect a
+// name which appears more than once in the same object when Options.DisallowDuplicateNames is set.
+type uniqueNames struct {
+	// The names of each object being consumed follow those of the objects it is nested inside, in spans, unless the
+	// object has too many to compare, in which case they are held in indexes instead. The first spans are held in
+	// inlineSpans, so that checking objects with only a few members allocates nothing beyond the uniqueNames itself,
+	// and the rest in moreSpans.
+	inlineSpans [maxComparedNames]nameSpan
+	moreSpans   []nameSpan
+	spanCount   int
+	indexes     []nameIndex
+	// copyNames is set when json may be overwritten before the names in it are finished with, as when reading from an
+	// io.Reader or redacting in place, in which case each name is copied to buffer. When reading from an io.Reader,
+	// a name may be split across refills, so while capturing is set, refill copies the part of the name from
+	// captureStart which it is about to overwrite to buffer first.
+	copyNames    bool
+	buffer       []byte
+	capturing    bool
+	captureStart int
+	// decodeBuffer is reused to decode names to look up in indexes.
+	decodeBuffer []byte
+}
+
+// newUniqueNames returns the state for a jsonValidator to check for duplicate names with, or nil if
+// options.DisallowDuplicateNames is not set.
+func newUniqueNames(options Options, copyNames bool) *uniqueNames {
+	if !options.DisallowDuplicateNames {
+		return nil
+	}
+	return &uniqueNames{copyNames: copyNames}
+}
+
+func (names *uniqueNames) span(index int) nameSpan {
+	if index < len(names.inlineSpans) {
+		return names.inlineSpans[index]
+	}
+	return names.moreSpans[index-len(names.inlineSpans)]
+}
+
+func (names *uniqueNames) push(span nameSpan) {
+	if names.spanCount < len(names.inlineSpans) {
+		names.inlineSpans[names.spanCount] = span
+	} else {
+		names.moreSpans = append(names.moreSpans[:names.spanCount-len(names.inlineSpans)], span)
+	}
+	names.spanCount += 1
+}
+
+// raw returns the bytes of the name between its quotes.
+func (names *uniqueNames) raw(json []byte, span nameSpan) []byte {
+	if names.copyNames {
+		return names.buffer[span.start+1 : span.end-1]
+	}
+	return json[span.start+1 : span.end-1]
+}
+
+// forgetNames forgets the names of the objects at depth or deeper, which have already been consumed, or are siblings
+// of an object being entered at depth.
+func (state *jsonValidator) forgetNames(depth int) {
+	names := state.uniqueNames
+	for names.spanCount != 0 {
+		span := names.span(names.spanCount - 1)
+		if span.depth < depth {
+			break
+		}
+		names.spanCount -= 1
+		if names.copyNames {
+			names.buffer = names.buffer[:span.start]
+		}
+	}
+	for len(names.indexes) != 0 && names.indexes[len(names.indexes)-1].depth >= depth {
+		names.indexes = names.indexes[:len(names.indexes)-1]
+	}
+}
+
+// consumeUniqueName consumes a name in the same manner as consumeName, and then checks that no member before it in the
+// same object has the same name.
+func (state *jsonValidator) consumeUniqueName() error {
+	names := state.uniqueNames
+	state.forgetNames(state.depth + 1)
+	span := nameSpan{depth: state.depth, offset: state.offsetBase + state.readIndex}
+	nameStart := state.readIndex
+	if names.copyNames {
+		span.start = len(names.buffer)
+		names.capturing, names.captureStart = state.reader != nil, nameStart
+	}
+	err := state.consumeString()
+	names.capturing = false
+	if err != nil {
+		return err
+	}
+	if names.copyNames {
+		names.buffer = append(names.buffer, state.json[names.captureStart:state.readIndex]...)
+		span.end = len(names.buffer)
+	} else {
+		span.start, span.end = nameStart, state.readIndex
+	}
+	raw := names.raw(state.json, span)
+	if len(names.indexes) != 0 && names.indexes[len(names.indexes)-1].depth == span.depth {
+		if names.copyNames {
+			names.buffer = names.buffer[:span.start]
+		}
+		return state.addIndexedName(names.indexes[len(names.indexes)-1].names, raw, span.offset)
+	}
+	compared := 0
+	for index := names.spanCount - 1; index >= 0; index-- {
+		other := names.span(index)
+		if other.depth != span.depth {
+			break
+		}
+		if equalRawNames(raw, names.raw(state.json, other)) {
+			return state.errorDuplicateName(raw, other.offset, span.offset)
+		}
+		compared += 1
+	}
+	if compared < maxComparedNames {
+		names.push(span)
+		return nil
+	}
+	// The object has too many names to keep comparing each new one with every other, so they are moved to a map.
+	index := nameIndex{depth: span.depth, names: make(map[string]int, 2*maxComparedNames)}
+	for spanIndex := names.spanCount - compared; spanIndex < names.spanCount; spanIndex++ {
+		other := names.span(spanIndex)
+		index.names[string(appendUnescaped(nil, names.raw(state.json, other)))] = other.offset
+	}
+	err = state.addIndexedName(index.names, raw, span.offset)
+	state.forgetNames(span.depth)
+	names.indexes = append(names.indexes, index)
+	return err
+}
+
+func (state *jsonValidator) addIndexedName(index map[string]int, raw []byte, offset int) error {
+	names := state.uniqueNames
+	names.decodeBuffer = appendUnescaped(names.decodeBuffer[:0], raw)
+	if firstOffset, found := index[string(names.decodeBuffer)]; found {
+		return state.errorDuplicateName(raw, firstOffset, offset)
+	}
+	index[string(names.decodeBuffer)] = offset
+	return nil
+}
+
+// errorDuplicateName returns an error for the name raw, which was first read at firstOffset and again at offset, both
+// of which are relative to the start of the whole JSON.
+func (state *jsonValidator) errorDuplicateName(raw []byte, firstOffset int, offset int) error {
+	// Names cannot contain newlines, so the line and column are found from the newlines before the name even if it
+	// began before json was last refilled.
+	localOffset := offset - state.offsetBase
+	state.countLines(max(localOffset, 0))
+	return &DuplicateNameError{
+		Name:        string(appendUnescaped(nil, raw)),
+		FirstOffset: firstOffset,
+		Offset:      offset,
+		Line:        state.lines + 1,
+		Column:      localOffset - state.lineStart + 1,
+	}
+}
+
+// equalRawNames reports whether a and b, the bytes of valid JSON names between their quotes, encode the same string
+// once their escape sequences have been decoded, without allocating.
+func equalRawNames(a []byte, b []byte) bool {
+	var aBuffer, bBuffer [utf8.UTFMax]byte
+	var aPiece, bPiece []byte
+	for {
+		if len(aPiece) == 0 {
+			aPiece, a = nextUnescapedPiece(a, &aBuffer)
+		}
+		if len(bPiece) == 0 {
+			bPiece, b = nextUnescapedPiece(b, &bBuffer)
+		}
+		if len(aPiece) == 0 || len(bPiece) == 0 {
+			return len(aPiece) == len(bPiece)
+		}
+		length := min(len(aPiece), len(bPiece))
+		if string(aPiece[:length]) != string(bPiece[:length]) {
+			return false
+		}
+		aPiece, bPiece = aPiece[length:], bPiece[length:]
+	}
+}
+
+// nextUnescapedPiece returns the bytes of raw up to its first escape sequence, or if it begins with one, the UTF-8
+// encoding of the rune it encodes, written to buffer, along with the rest of raw.
+func nextUnescapedPiece(raw []byte, buffer *[utf8.UTFMax]byte) ([]byte, []byte) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	if raw[0] != '\\' {
+		escapeIndex := bytes.IndexByte(raw, '\\')
+		if escapeIndex == -1 {
+			return raw, nil
+		}
+		return raw[:escapeIndex], raw[escapeIndex:]
+	}
+	decoded, escapeLength := decodeEscape(raw)
+	return buffer[:utf8.EncodeRune(buffer[:], decoded)], raw[escapeLength:]
+}
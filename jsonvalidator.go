@@ -1,33 +1,69 @@
 package jsonbytes
 
-import (
-	"errors"
-	"fmt"
-)
+import "io"
+
+// readerBufferSize is the number of bytes a jsonValidator reading from an io.Reader will hold in memory at once.
+const readerBufferSize = 4096
 
 type jsonValidator struct {
-	json       []byte
-	jsonLength int
-	readIndex  int
-	readHead   byte
+	options        Options
+	json           []byte
+	jsonLength     int
+	readIndex      int
+	readHead       byte
+	depth          int
+	maxDepth       int
+	lines          int
+	lineStart      int
+	linesCountedTo int
+	// reader is only set when validating from an io.Reader, in which case json is a fixed size buffer that is refilled
+	// from reader whenever readIndex reaches jsonLength, and offsetBase is the offset of json[0] in the whole stream.
+	reader     io.Reader
+	readError  error
+	offsetBase int
+	// uniqueNames is only set when options.DisallowDuplicateNames is, so that validators which do not check for
+	// duplicate names do not carry its state around.
+	uniqueNames *uniqueNames
 }
 
-func newJsonValidator(json []byte) (*jsonValidator, error) {
+func newJsonValidator(json []byte, options Options) (*jsonValidator, error) {
 	if len(json) == 0 {
-		return nil, errors.New("jsonvalidator needs more than zero bytes")
+		return nil, &SyntaxError{Kind: SyntaxErrorEmptyInput, Line: 1, Column: 1}
 	}
 	return &jsonValidator{
-		json:       json,
-		readHead:   json[0],
-		jsonLength: len(json),
-		readIndex:  0,
+		options:     options,
+		maxDepth:    options.maxDepth(),
+		json:        json,
+		readHead:    json[0],
+		jsonLength:  len(json),
+		readIndex:   0,
+		uniqueNames: newUniqueNames(options, false),
 	}, nil
 }
 
+func newJsonReaderValidator(reader io.Reader, options Options) (*jsonValidator, error) {
+	state := &jsonValidator{
+		options:  options,
+		maxDepth: options.maxDepth(),
+		json:     make([]byte, readerBufferSize),
+		reader:   reader,
+		// The names in json are overwritten when it is refilled, so they must be copied to be checked for duplicates.
+		uniqueNames: newUniqueNames(options, true),
+	}
+	state.refill()
+	if state.jsonLength == 0 {
+		if state.readError != nil {
+			return nil, state.readError
+		}
+		return nil, &SyntaxError{Kind: SyntaxErrorEmptyInput, Line: 1, Column: 1}
+	}
+	return state, nil
+}
+
 func (state *jsonValidator) consumeValue() error {
 	state.consumeWhitespace()
 	if state.readIndex == state.jsonLength {
-		return errors.New("read head ran out of json")
+		return state.errorUnexpectedEnd()
 	}
 	var err error
 	switch state.readHead {
@@ -56,127 +92,298 @@ func (state *jsonValidator) consumeValue() error {
 }
 
 func (state *jsonValidator) consumeObject() error {
+	err := state.enterContainer()
+	if err != nil {
+		return err
+	}
 	state.readUnsafe()
 	state.consumeWhitespace()
 	if state.readIndex == state.jsonLength {
-		return errors.New("read head ran out of json")
+		return state.errorUnexpectedEnd()
+	}
+	if state.readHead == '}' {
+		state.depth -= 1
+		return state.consumeByte('}')
 	}
-	var err error
 	for {
+		err = state.consumeName()
+		if err != nil {
+			return err
+		}
+		state.consumeWhitespace()
+		if state.readIndex == state.jsonLength {
+			return state.errorUnexpectedEnd()
+		}
+		err = state.consumeByte(':')
+		if err != nil {
+			return err
+		}
+		err = state.consumeValue()
+		if err != nil {
+			return err
+		}
+		if state.readIndex == state.jsonLength {
+			return state.errorUnexpectedEnd()
+		}
 		switch state.readHead {
 		case ',':
-			err = state.consumeByte(',')
-			if err != nil {
-				return err
+			state.readUnsafe()
+			state.consumeWhitespace()
+			if state.readIndex == state.jsonLength {
+				return state.errorUnexpectedEnd()
 			}
 		case '}':
+			state.depth -= 1
 			return state.consumeByte('}')
 		default:
-			state.consumeWhitespace()
-			if state.readIndex == state.jsonLength {
-				return errors.New("read head ran out of json")
-			}
-			err = state.consumeName()
-			if err != nil {
-				return err
-			}
-			state.consumeWhitespace()
-			if state.readIndex == state.jsonLength {
-				return errors.New("read head ran out of json")
-			}
-			err = state.consumeByte(':')
-			if err != nil {
-				return err
-			}
-			err = state.consumeValue()
-			if err != nil {
-				return err
-			}
+			return state.errorUnexpectedCharacter("any of ,}")
 		}
 	}
 }
 
 func (state *jsonValidator) consumeArray() error {
+	err := state.enterContainer()
+	if err != nil {
+		return err
+	}
 	state.readUnsafe()
 	state.consumeWhitespace()
 	if state.readIndex == state.jsonLength {
-		return errors.New("read head ran out of json")
+		return state.errorUnexpectedEnd()
+	}
+	if state.readHead == ']' {
+		state.depth -= 1
+		return state.consumeByte(']')
 	}
-	var err error
 	for {
+		err = state.consumeValue()
+		if err != nil {
+			return err
+		}
+		if state.readIndex == state.jsonLength {
+			return state.errorUnexpectedEnd()
+		}
 		switch state.readHead {
+		case ',':
+			state.readUnsafe()
 		case ']':
+			state.depth -= 1
 			return state.consumeByte(']')
-		case ',':
-			err = state.consumeByte(',')
-			if err != nil {
-				return err
-			}
 		default:
-			err = state.consumeValue()
-			if err != nil {
-				return err
-			}
+			return state.errorUnexpectedCharacter("any of ,]")
 		}
 	}
 }
 
+// enterContainer must be called before consuming the opening byte of an object or array, and depth decremented again
+// before consuming its closing byte.
+func (state *jsonValidator) enterContainer() error {
+	if state.depth == state.maxDepth {
+		return state.syntaxError(SyntaxErrorMaxDepthExceeded, state.readIndex, "")
+	}
+	state.depth += 1
+	if state.uniqueNames != nil {
+		state.forgetNames(state.depth)
+	}
+	return nil
+}
+
 func (state *jsonValidator) consumeWhitespace() {
-	for state.readIndex < state.jsonLength && (state.readHead == ' ' ||
-		state.readHead == '\t' ||
-		state.readHead == '\n' ||
-		state.readHead == '\r') {
+	for state.readIndex < state.jsonLength && isWhitespace(state.readHead) {
+		// The rest of the run of whitespace in json is skipped over without loading each byte into readHead, and then
+		// readUnsafe reads the byte after it, refilling json first if need be.
+		json := state.json[:state.jsonLength]
+		index := state.readIndex + 1
+		for index < len(json) && isWhitespace(json[index]) {
+			index++
+		}
+		state.readIndex = index - 1
 		state.readUnsafe()
 	}
 }
 
+func isWhitespace(character byte) bool {
+	return character == ' ' || character == '\t' || character == '\n' || character == '\r'
+}
+
 func (state *jsonValidator) consumeString() error {
 	state.readUnsafe()
-	prevHead := byte(0)
-	for (state.readHead != '"' || prevHead == '\\') && state.readIndex < state.jsonLength {
-		if state.readHead < 32 || state.readHead == 127 {
+	for state.readHead != '"' && state.readIndex < state.jsonLength {
+		if state.readHead < 32 {
 			return state.errorUnexpectedCharacter("any codepoint except \" or \\ or control characters")
 		}
-		if prevHead == '\\' {
-			switch state.readHead {
-			case '"', '/', '\\', 'b', 'f', 'n', 'r', 't':
-				break
-			case 'u':
-				for i := 0; i < 4 && state.readIndex < state.jsonLength; i++ {
-					state.readUnsafe()
-					if state.readIndex == state.jsonLength {
-						return state.errorUnexpectedCharacter("4 hex digits")
-					}
-					if !(('0' <= state.readHead && state.readHead <= '9') ||
-						('A' <= state.readHead && state.readHead <= 'F') ||
-						('a' <= state.readHead && state.readHead <= 'f')) {
-						return state.errorUnexpectedCharacter("4 hex digits")
-					}
-				}
-			default:
-				return state.errorUnexpectedCharacter("any of \"/\\bfnrtu")
+		if state.readHead == '\\' {
+			if state.options.ValidateUTF8 {
+				return state.consumeStringUTF8()
+			}
+			_, err := state.consumeEscape()
+			if err != nil {
+				return err
+			}
+		} else if state.readHead >= 0x80 && state.options.ValidateUTF8 {
+			return state.consumeStringUTF8()
+		} else {
+			// The rest of the run of bytes which need no checks beyond those of the loop is skipped over in the same
+			// manner as in consumeWhitespace.
+			plainBytes := &plainStringBytes
+			if state.options.ValidateUTF8 {
+				plainBytes = &plainASCIIStringBytes
 			}
+			json := state.json[:state.jsonLength]
+			index := state.readIndex + 1
+			for index < len(json) && plainBytes[json[index]] {
+				index++
+			}
+			state.readIndex = index - 1
+			state.readUnsafe()
+		}
+	}
+	return state.consumeByte('"')
+}
+
+// plainStringBytes holds whether each byte can appear in a string without ending it, beginning an escape sequence, or
+// being a control character, and plainASCIIStringBytes the same for when Options.ValidateUTF8 is set, so that the
+// bytes of multi-byte encodings are checked too.
+var plainStringBytes, plainASCIIStringBytes = func() (plain [256]bool, plainASCII [256]bool) {
+	for character := 32; character < 256; character++ {
+		plain[character] = character != '"' && character != '\\'
+		plainASCII[character] = plain[character] && character < 0x80
+	}
+	return plain, plainASCII
+}()
 
+// consumeStringUTF8 consumes the remainder of a string in the same way as consumeString, but also checks that it is
+// valid UTF-8 and that its \u escapes are correctly paired UTF-16 surrogates. consumeString hands over to it at the
+// first byte which needs such checks, so that strings of only ASCII characters pay nothing extra for them.
+func (state *jsonValidator) consumeStringUTF8() error {
+	highSurrogateOffset := -1
+	for state.readHead != '"' && state.readIndex < state.jsonLength {
+		if state.readHead < 32 {
+			return state.errorUnexpectedCharacter("any codepoint except \" or \\ or control characters")
 		}
-		prevHead = state.readHead
-		state.readUnsafe()
+		if state.readHead == '\\' {
+			// The offset is stored relative to the start of the stream, as json may be refilled whilst the escape is
+			// consumed.
+			escapeOffset := state.offsetBase + state.readIndex
+			codeUnit, err := state.consumeEscape()
+			if err != nil {
+				return err
+			}
+			if highSurrogateOffset != -1 {
+				if codeUnit < 0xDC00 || codeUnit > 0xDFFF {
+					return state.errorUnpairedSurrogate(highSurrogateOffset)
+				}
+				highSurrogateOffset = -1
+			} else if 0xD800 <= codeUnit && codeUnit <= 0xDBFF {
+				highSurrogateOffset = escapeOffset
+			} else if 0xDC00 <= codeUnit && codeUnit <= 0xDFFF {
+				return state.errorUnpairedSurrogate(escapeOffset)
+			}
+			continue
+		}
+		if highSurrogateOffset != -1 {
+			return state.errorUnpairedSurrogate(highSurrogateOffset)
+		}
+		if state.readHead >= 0x80 {
+			err := state.consumeUTF8()
+			if err != nil {
+				return err
+			}
+		} else {
+			state.readUnsafe()
+		}
+	}
+	if highSurrogateOffset != -1 {
+		return state.errorUnpairedSurrogate(highSurrogateOffset)
 	}
 	return state.consumeByte('"')
 }
 
-func (state *jsonValidator) consumeName() error {
+// consumeUTF8 consumes a multi-byte UTF-8 encoded codepoint, rejecting overlong encodings, encoded surrogates and
+// codepoints greater than U+10FFFF, as unicode.org's table of well-formed UTF-8 byte sequences does.
+func (state *jsonValidator) consumeUTF8() error {
+	continuationBytes := 0
+	secondByteMin, secondByteMax := byte(0x80), byte(0xBF)
+	switch {
+	case 0xC2 <= state.readHead && state.readHead <= 0xDF:
+		continuationBytes = 1
+	case state.readHead == 0xE0:
+		continuationBytes = 2
+		secondByteMin = 0xA0
+	case 0xE1 <= state.readHead && state.readHead <= 0xEC, state.readHead == 0xEE, state.readHead == 0xEF:
+		continuationBytes = 2
+	case state.readHead == 0xED:
+		continuationBytes = 2
+		secondByteMax = 0x9F
+	case state.readHead == 0xF0:
+		continuationBytes = 3
+		secondByteMin = 0x90
+	case 0xF1 <= state.readHead && state.readHead <= 0xF3:
+		continuationBytes = 3
+	case state.readHead == 0xF4:
+		continuationBytes = 3
+		secondByteMax = 0x8F
+	default:
+		return state.syntaxError(SyntaxErrorInvalidUTF8, state.readIndex, "")
+	}
+	for i := 0; i < continuationBytes; i++ {
+		state.readUnsafe()
+		if state.readIndex == state.jsonLength {
+			return state.errorUnexpectedCharacter("UTF-8 continuation byte")
+		}
+		if state.readHead < secondByteMin || state.readHead > secondByteMax {
+			return state.syntaxError(SyntaxErrorInvalidUTF8, state.readIndex, "")
+		}
+		secondByteMin, secondByteMax = 0x80, 0xBF
+	}
 	state.readUnsafe()
-	prevHead := byte(0)
-	for state.readHead != '"' || prevHead == '\\' {
-		prevHead = state.readHead
-		state.readIndex += 1
-		if state.readIndex > state.jsonLength {
-			return state.errorUnexpectedCharacter("\"")
-		} else if state.readIndex != state.jsonLength {
-			state.readHead = state.json[state.readIndex]
+	return nil
+}
+
+// consumeEscape consumes an escape sequence, including its leading backslash. If it is a \u escape, the UTF-16 code
+// unit it encodes is returned, else -1 is returned.
+func (state *jsonValidator) consumeEscape() (int, error) {
+	state.readUnsafe()
+	if state.readIndex == state.jsonLength {
+		return -1, state.errorUnexpectedCharacter("any of \"/\\bfnrtu")
+	}
+	codeUnit := -1
+	switch state.readHead {
+	case '"', '/', '\\', 'b', 'f', 'n', 'r', 't':
+		break
+	case 'u':
+		codeUnit = 0
+		for i := 0; i < 4; i++ {
+			state.readUnsafe()
+			if state.readIndex == state.jsonLength {
+				return -1, state.errorUnexpectedCharacter("4 hex digits")
+			}
+			switch {
+			case '0' <= state.readHead && state.readHead <= '9':
+				codeUnit = codeUnit<<4 | int(state.readHead-'0')
+			case 'A' <= state.readHead && state.readHead <= 'F':
+				codeUnit = codeUnit<<4 | int(state.readHead-'A'+10)
+			case 'a' <= state.readHead && state.readHead <= 'f':
+				codeUnit = codeUnit<<4 | int(state.readHead-'a'+10)
+			default:
+				return -1, state.errorUnexpectedCharacter("4 hex digits")
+			}
 		}
+	default:
+		return -1, state.errorUnexpectedCharacter("any of \"/\\bfnrtu")
 	}
-	return state.consumeByte('"')
+	state.readUnsafe()
+	return codeUnit, nil
+}
+
+func (state *jsonValidator) consumeName() error {
+	if state.readHead != '"' {
+		return state.errorUnexpectedCharacter("\"")
+	}
+	if state.uniqueNames != nil {
+		return state.consumeUniqueName()
+	}
+	return state.consumeString()
 }
 
 func (state *jsonValidator) consumeNumber() error {
@@ -203,12 +410,13 @@ func (state *jsonValidator) consumeNumber() error {
 	}
 	if state.readHead == 'E' || state.readHead == 'e' {
 		state.readUnsafe()
-		if state.readHead != '+' && state.readHead != '-' {
-			return state.errorUnexpectedCharacter("+ or -")
+		if state.readHead == '+' || state.readHead == '-' {
+			state.readUnsafe()
+		} else if state.readIndex == state.jsonLength || 48 > state.readHead || state.readHead > 57 {
+			return state.errorUnexpectedCharacter("any of +-0123456789")
 		}
-		state.readUnsafe()
-		if state.readIndex == state.jsonLength {
-			return errors.New("read head ran out of json")
+		if state.readIndex == state.jsonLength || 48 > state.readHead || state.readHead > 57 {
+			return state.errorUnexpectedCharacter("any of 0123456789")
 		}
 		for 48 <= state.readHead && state.readHead <= 57 && state.readIndex < state.jsonLength {
 			state.readUnsafe()
@@ -252,12 +460,10 @@ func (state *jsonValidator) consumeByte(expectedByte byte) error {
 	if state.readHead != expectedByte {
 		return state.errorUnexpectedCharacter(string(expectedByte))
 	}
-	state.readIndex += 1
-	if state.readIndex > state.jsonLength {
-		return errors.New("read head ran out of json")
-	} else if state.readIndex != state.jsonLength {
-		state.readHead = state.json[state.readIndex]
+	if state.readIndex == state.jsonLength {
+		return state.errorUnexpectedEnd()
 	}
+	state.readUnsafe()
 	return nil
 }
 
@@ -265,15 +471,104 @@ func (state *jsonValidator) readUnsafe() {
 	state.readIndex += 1
 	if state.readIndex != state.jsonLength {
 		state.readHead = state.json[state.readIndex]
+	} else {
+		state.refill()
+	}
+}
+
+// refill replaces the contents of json with the next bytes read from reader. If reader has no more bytes to give,
+// json is left as it is and reader is set to nil, so that readIndex stays at jsonLength to signal the end of the json.
+func (state *jsonValidator) refill() {
+	if state.reader == nil {
+		return
+	}
+	state.countLines(state.jsonLength)
+	names := state.uniqueNames
+	if names != nil && names.capturing {
+		names.buffer = append(names.buffer, state.json[names.captureStart:]...)
+		names.captureStart = state.jsonLength
+	}
+	for {
+		n, err := state.reader.Read(state.json[:cap(state.json)])
+		if n > 0 {
+			state.offsetBase += state.jsonLength
+			state.lineStart -= state.jsonLength
+			state.linesCountedTo = 0
+			state.json = state.json[:n]
+			state.jsonLength = n
+			state.readIndex = 0
+			state.readHead = state.json[0]
+			if names != nil {
+				names.captureStart = 0
+			}
+			return
+		}
+		if err != nil {
+			if err != io.EOF {
+				state.readError = err
+			}
+			state.reader = nil
+			state.json = state.json[:state.jsonLength]
+			return
+		}
 	}
 }
 
 func (state *jsonValidator) errorUnexpectedCharacter(expectedBytes string) error {
 	if state.readIndex >= state.jsonLength {
-		return fmt.Errorf("expected %s but reached end of json", expectedBytes)
+		return state.syntaxError(SyntaxErrorUnexpectedEnd, state.jsonLength, expectedBytes)
+	}
+	return state.syntaxError(SyntaxErrorUnexpectedCharacter, state.readIndex, expectedBytes)
+}
+
+func (state *jsonValidator) errorUnexpectedEnd() error {
+	return state.syntaxError(SyntaxErrorUnexpectedEnd, state.jsonLength, "")
+}
+
+// errorUnpairedSurrogate returns an error for a \u escape of a UTF-16 surrogate which was not correctly paired. As
+// the escape may have been read before json was last refilled, offset is relative to the start of the stream rather
+// than to json.
+func (state *jsonValidator) errorUnpairedSurrogate(offset int) error {
+	return state.syntaxError(SyntaxErrorUnpairedSurrogate, offset-state.offsetBase, "")
+}
+
+func (state *jsonValidator) errorTrailingData() error {
+	return state.syntaxError(SyntaxErrorTrailingData, state.readIndex, "")
+}
+
+// syntaxError constructs a SyntaxError for the byte at offset. The line and column are only computed here, so that
+// the cost of finding them is never paid when a value is valid.
+func (state *jsonValidator) syntaxError(kind SyntaxErrorKind, offset int, expected string) *SyntaxError {
+	if offset > state.jsonLength {
+		offset = state.jsonLength
+	}
+	state.countLines(offset)
+	err := &SyntaxError{
+		Kind:     kind,
+		Offset:   state.offsetBase + offset,
+		Line:     state.lines + 1,
+		Column:   offset - state.lineStart + 1,
+		Expected: expected,
+	}
+	switch kind {
+	case SyntaxErrorUnexpectedCharacter, SyntaxErrorTrailingData, SyntaxErrorInvalidUTF8, SyntaxErrorMaxDepthExceeded:
+		err.Found = state.json[offset]
+	case SyntaxErrorUnpairedSurrogate:
+		err.Found = '\\'
+	}
+	return err
+}
+
+// countLines counts the newlines in json between the last index counted and end. Callers that overwrite json as they
+// read it must call countLines before overwriting any newlines, otherwise syntaxError will report the wrong line.
+func (state *jsonValidator) countLines(end int) {
+	for i := state.linesCountedTo; i < end; i++ {
+		if state.json[i] == '\n' {
+			state.lines += 1
+			state.lineStart = i + 1
+		}
+	}
+	if end > state.linesCountedTo {
+		state.linesCountedTo = end
 	}
-	return fmt.Errorf(
-		"expected %s at index %d but read '%s'",
-		expectedBytes, state.readIndex, string(state.readHead),
-	)
 }
@@ -0,0 +1,220 @@
+package jsonbytes
+
+import "errors"
+
+// Visitor receives the events of Walk. The raw bytes passed to OnName, OnString and OnNumber are exactly as they
+// appear in the JSON, including the quotes and escape sequences of names and strings, and are subslices of it rather
+// than copies, so they must not be retained after the method returns unless the JSON will not be modified.
+//
+// If a method returns an error, Walk stops and returns the same error, except for SkipSubtree and StopWalk.
+type Visitor interface {
+	OnObjectStart() error
+	OnObjectEnd() error
+	OnName(raw []byte) error
+	OnArrayStart() error
+	OnArrayEnd() error
+	OnString(raw []byte) error
+	OnNumber(raw []byte) error
+	OnBool(value bool) error
+	OnNull() error
+}
+
+// SkipSubtree may be returned by a Visitor to skip part of the JSON. Returned by OnObjectStart or OnArrayStart, the
+// rest of the object or array is skipped, including its end. Returned by OnName, the value of the member is skipped.
+// Returned by any other method, it is treated the same as nil. Skipped JSON is still validated.
+var SkipSubtree = errors.New("skip subtree")
+
+// StopWalk may be returned by a Visitor to stop Walk straight away, in which case Walk returns nil without reading
+// any more of the JSON.
+var StopWalk = errors.New("stop walk")
+
+// jsonWalker calls the methods of visitor for each part of json as it is consumed.
+type jsonWalker struct {
+	// jsonValidator is held by value for the same reason as in jsonRedactor.
+	jsonValidator jsonValidator
+	visitor       Visitor
+}
+
+// walk consumes the whole of json, and returns nil if it was a valid JSON value, or if the visitor stopped the walk.
+func (state *jsonWalker) walk() error {
+	err := state.consumeValue()
+	if err == StopWalk {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if state.jsonValidator.readIndex != state.jsonValidator.jsonLength {
+		return state.jsonValidator.errorTrailingData()
+	}
+	return nil
+}
+
+func (state *jsonWalker) consumeValue() error {
+	state.jsonValidator.consumeWhitespace()
+	if state.jsonValidator.readIndex == state.jsonValidator.jsonLength {
+		return state.jsonValidator.errorUnexpectedEnd()
+	}
+	valueStart := state.jsonValidator.readIndex
+	var err error
+	switch state.jsonValidator.readHead {
+	case '"':
+		err = state.jsonValidator.consumeString()
+		if err == nil {
+			err = state.visitor.OnString(state.jsonValidator.json[valueStart:state.jsonValidator.readIndex])
+		}
+	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		err = state.jsonValidator.consumeNumber()
+		if err == nil {
+			err = state.visitor.OnNumber(state.jsonValidator.json[valueStart:state.jsonValidator.readIndex])
+		}
+	case '{':
+		err = state.consumeObject()
+	case '[':
+		err = state.consumeArray()
+	case 't':
+		err = state.jsonValidator.consumeTrue()
+		if err == nil {
+			err = state.visitor.OnBool(true)
+		}
+	case 'f':
+		err = state.jsonValidator.consumeFalse()
+		if err == nil {
+			err = state.visitor.OnBool(false)
+		}
+	case 'n':
+		err = state.jsonValidator.consumeNull()
+		if err == nil {
+			err = state.visitor.OnNull()
+		}
+	default:
+		return state.jsonValidator.errorUnexpectedCharacter("any of \"10123456789{[tfn")
+	}
+	if err != nil && err != SkipSubtree {
+		return err
+	}
+	state.jsonValidator.consumeWhitespace()
+	return nil
+}
+
+func (state *jsonWalker) consumeObject() error {
+	err := state.jsonValidator.enterContainer()
+	if err != nil {
+		return err
+	}
+	err = state.visitor.OnObjectStart()
+	if err == SkipSubtree {
+		state.jsonValidator.depth -= 1
+		return state.jsonValidator.consumeObject()
+	}
+	if err != nil {
+		return err
+	}
+	state.jsonValidator.readUnsafe()
+	state.jsonValidator.consumeWhitespace()
+	if state.jsonValidator.readIndex == state.jsonValidator.jsonLength {
+		return state.jsonValidator.errorUnexpectedEnd()
+	}
+	if state.jsonValidator.readHead == '}' {
+		state.jsonValidator.depth -= 1
+		return state.consumeContainerEnd('}')
+	}
+	for {
+		nameStart := state.jsonValidator.readIndex
+		err = state.jsonValidator.consumeName()
+		if err != nil {
+			return err
+		}
+		err = state.visitor.OnName(state.jsonValidator.json[nameStart:state.jsonValidator.readIndex])
+		if err != nil && err != SkipSubtree {
+			return err
+		}
+		skipValue := err == SkipSubtree
+		state.jsonValidator.consumeWhitespace()
+		if state.jsonValidator.readIndex == state.jsonValidator.jsonLength {
+			return state.jsonValidator.errorUnexpectedEnd()
+		}
+		err = state.jsonValidator.consumeByte(':')
+		if err != nil {
+			return err
+		}
+		if skipValue {
+			err = state.jsonValidator.consumeValue()
+		} else {
+			err = state.consumeValue()
+		}
+		if err != nil {
+			return err
+		}
+		if state.jsonValidator.readIndex == state.jsonValidator.jsonLength {
+			return state.jsonValidator.errorUnexpectedEnd()
+		}
+		switch state.jsonValidator.readHead {
+		case ',':
+			state.jsonValidator.readUnsafe()
+			state.jsonValidator.consumeWhitespace()
+			if state.jsonValidator.readIndex == state.jsonValidator.jsonLength {
+				return state.jsonValidator.errorUnexpectedEnd()
+			}
+		case '}':
+			state.jsonValidator.depth -= 1
+			return state.consumeContainerEnd('}')
+		default:
+			return state.jsonValidator.errorUnexpectedCharacter("any of ,}")
+		}
+	}
+}
+
+func (state *jsonWalker) consumeArray() error {
+	err := state.jsonValidator.enterContainer()
+	if err != nil {
+		return err
+	}
+	err = state.visitor.OnArrayStart()
+	if err == SkipSubtree {
+		state.jsonValidator.depth -= 1
+		return state.jsonValidator.consumeArray()
+	}
+	if err != nil {
+		return err
+	}
+	state.jsonValidator.readUnsafe()
+	state.jsonValidator.consumeWhitespace()
+	if state.jsonValidator.readIndex == state.jsonValidator.jsonLength {
+		return state.jsonValidator.errorUnexpectedEnd()
+	}
+	if state.jsonValidator.readHead == ']' {
+		state.jsonValidator.depth -= 1
+		return state.consumeContainerEnd(']')
+	}
+	for {
+		err = state.consumeValue()
+		if err != nil {
+			return err
+		}
+		if state.jsonValidator.readIndex == state.jsonValidator.jsonLength {
+			return state.jsonValidator.errorUnexpectedEnd()
+		}
+		switch state.jsonValidator.readHead {
+		case ',':
+			state.jsonValidator.readUnsafe()
+		case ']':
+			state.jsonValidator.depth -= 1
+			return state.consumeContainerEnd(']')
+		default:
+			return state.jsonValidator.errorUnexpectedCharacter("any of ,]")
+		}
+	}
+}
+
+// consumeContainerEnd consumes the closing byte of an object or array, and then tells the visitor it has ended.
+func (state *jsonWalker) consumeContainerEnd(closingByte byte) error {
+	err := state.jsonValidator.consumeByte(closingByte)
+	if err != nil {
+		return err
+	}
+	if closingByte == '}' {
+		return state.visitor.OnObjectEnd()
+	}
+	return state.visitor.OnArrayEnd()
+}
@@ -0,0 +1,333 @@
+// Package schema validates JSON documents against a JSON Schema (draft 2020-12), reading each document in a single pass
+// of the jsonbytes Tokenizer rather than unmarshalling it.
+//
+// Only the keywords most often used to check the shape of a document are supported: type, enum, const, minimum,
+// maximum, exclusiveMinimum, exclusiveMaximum, minLength, maxLength, pattern, properties, additionalProperties,
+// required, minProperties, maxProperties, items, minItems and maxItems. Other keywords are ignored, as unknown
+// keywords are by the specification. Patterns are compiled with the regexp package, whose syntax is almost, but not
+// quite, that of the ECMA-262 regular expressions the specification calls for.
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Schema is a compiled JSON Schema, which can be used to validate any number of documents, concurrently if need be.
+type Schema struct {
+	root *node
+}
+
+// Violation is a way in which a document does not conform to a Schema.
+type Violation struct {
+	// Pointer is the JSON Pointer (rfc6901) of the value in the document which violates the schema, which is empty
+	// for the whole document.
+	Pointer string
+	// Keyword is the keyword of the schema which the value violates, such as "type" or "required", or "false" if the
+	// value is not allowed at all.
+	Keyword string
+	// Message describes the violation.
+	Message string
+}
+
+func (violation Violation) String() string {
+	return fmt.Sprintf("%q: %s", violation.Pointer, violation.Message)
+}
+
+// ValidationError is returned by Validate when a document is valid JSON, but does not conform to the Schema. It holds
+// every violation in the document, in the order they were found.
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (err *ValidationError) Error() string {
+	violations := make([]string, len(err.Violations))
+	for index, violation := range err.Violations {
+		violations[index] = violation.String()
+	}
+	return strings.Join(violations, "\n")
+}
+
+// valueType is a set of the types of the type keyword.
+type valueType int
+
+const (
+	typeNull valueType = 1 << iota
+	typeBoolean
+	typeObject
+	typeArray
+	typeNumber
+	typeInteger
+	typeString
+	typeAny valueType = 1<<iota - 1
+)
+
+var typeNames = []string{"null", "boolean", "object", "array", "number", "integer", "string"}
+
+func (types valueType) String() string {
+	var names []string
+	for index, name := range typeNames {
+		if types&(1<<index) != 0 {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, " or ")
+}
+
+// node is a compiled schema or subschema. A nil *node allows any value, as does the true schema.
+type node struct {
+	// never is set for the false schema, which allows no value.
+	never bool
+	types valueType
+	// enum holds the values of the enum keyword, and the const keyword as a single value, unmarshalled with
+	// json.Decoder.UseNumber. hasEnum is set if either was present, and enumKeyword is which.
+	enum        []any
+	hasEnum     bool
+	enumKeyword string
+	// The number keywords are nil when absent.
+	minimum          *big.Float
+	maximum          *big.Float
+	exclusiveMinimum *big.Float
+	exclusiveMaximum *big.Float
+	// The length and count keywords are -1 when absent.
+	minLength     int
+	maxLength     int
+	pattern       *regexp.Regexp
+	properties    map[string]*node
+	additional    *node
+	required      []string
+	minProperties int
+	maxProperties int
+	items         *node
+	minItems      int
+	maxItems      int
+}
+
+// Compile compiles schemaJson, a JSON Schema, so that it can be used to validate documents. An error is returned if
+// schemaJson is not valid JSON, or if a supported keyword has a value of the wrong type, such as a pattern which is
+// not a valid regular expression.
+func Compile(schemaJson []byte) (*Schema, error) {
+	decoder := json.NewDecoder(bytes.NewReader(schemaJson))
+	decoder.UseNumber()
+	var schemaValue any
+	err := decoder.Decode(&schemaValue)
+	if err != nil {
+		return nil, fmt.Errorf("schema is not valid JSON: %w", err)
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("schema is not valid JSON: more than one value")
+	}
+	root, err := compileNode(schemaValue, "")
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{root: root}, nil
+}
+
+// compileNode compiles the schema or subschema schemaValue, at the JSON Pointer pointer in the schema.
+func compileNode(schemaValue any, pointer string) (*node, error) {
+	switch schemaValue := schemaValue.(type) {
+	case bool:
+		if schemaValue {
+			return nil, nil
+		}
+		return &node{never: true}, nil
+	case map[string]any:
+		compiled := &node{
+			types:         typeAny,
+			minLength:     -1,
+			maxLength:     -1,
+			minProperties: -1,
+			maxProperties: -1,
+			minItems:      -1,
+			maxItems:      -1,
+		}
+		for keyword, value := range schemaValue {
+			err := compiled.compileKeyword(keyword, value, pointer+"/"+escapePointer(keyword))
+			if err != nil {
+				return nil, err
+			}
+		}
+		return compiled, nil
+	}
+	return nil, fmt.Errorf("schema at %q must be an object or boolean", pointer)
+}
+
+// compileKeyword compiles a single keyword of a schema, whose value is at the JSON Pointer pointer in the schema.
+func (compiled *node) compileKeyword(keyword string, value any, pointer string) error {
+	var err error
+	switch keyword {
+	case "type":
+		compiled.types, err = compileTypes(value, pointer)
+	case "enum":
+		values, isArray := value.([]any)
+		if !isArray {
+			return fmt.Errorf("enum at %q must be an array", pointer)
+		}
+		compiled.enum, compiled.hasEnum, compiled.enumKeyword = values, true, keyword
+	case "const":
+		compiled.enum, compiled.hasEnum, compiled.enumKeyword = []any{value}, true, keyword
+	case "minimum":
+		compiled.minimum, err = compileNumber(keyword, value, pointer)
+	case "maximum":
+		compiled.maximum, err = compileNumber(keyword, value, pointer)
+	case "exclusiveMinimum":
+		compiled.exclusiveMinimum, err = compileNumber(keyword, value, pointer)
+	case "exclusiveMaximum":
+		compiled.exclusiveMaximum, err = compileNumber(keyword, value, pointer)
+	case "minLength":
+		compiled.minLength, err = compileCount(keyword, value, pointer)
+	case "maxLength":
+		compiled.maxLength, err = compileCount(keyword, value, pointer)
+	case "minProperties":
+		compiled.minProperties, err = compileCount(keyword, value, pointer)
+	case "maxProperties":
+		compiled.maxProperties, err = compileCount(keyword, value, pointer)
+	case "minItems":
+		compiled.minItems, err = compileCount(keyword, value, pointer)
+	case "maxItems":
+		compiled.maxItems, err = compileCount(keyword, value, pointer)
+	case "pattern":
+		pattern, isString := value.(string)
+		if !isString {
+			return fmt.Errorf("pattern at %q must be a string", pointer)
+		}
+		compiled.pattern, err = regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("pattern at %q is not a valid regular expression: %w", pointer, err)
+		}
+	case "properties":
+		properties, isObject := value.(map[string]any)
+		if !isObject {
+			return fmt.Errorf("properties at %q must be an object", pointer)
+		}
+		compiled.properties = make(map[string]*node, len(properties))
+		for name, property := range properties {
+			compiled.properties[name], err = compileNode(property, pointer+"/"+escapePointer(name))
+			if err != nil {
+				return err
+			}
+		}
+	case "additionalProperties":
+		compiled.additional, err = compileNode(value, pointer)
+	case "items":
+		compiled.items, err = compileNode(value, pointer)
+	case "required":
+		names, isArray := value.([]any)
+		if !isArray {
+			return fmt.Errorf("required at %q must be an array of strings", pointer)
+		}
+		for _, name := range names {
+			name, isString := name.(string)
+			if !isString {
+				return fmt.Errorf("required at %q must be an array of strings", pointer)
+			}
+			compiled.required = append(compiled.required, name)
+		}
+	}
+	return err
+}
+
+func compileTypes(value any, pointer string) (valueType, error) {
+	var names []any
+	switch value := value.(type) {
+	case string:
+		names = []any{value}
+	case []any:
+		names = value
+	default:
+		return 0, fmt.Errorf("type at %q must be a string or an array of strings", pointer)
+	}
+	var types valueType
+	for _, name := range names {
+		index := -1
+		for typeIndex, typeName := range typeNames {
+			if name == typeName {
+				index = typeIndex
+			}
+		}
+		if index == -1 {
+			return 0, fmt.Errorf("type at %q must be one of %s", pointer, strings.Join(typeNames, ", "))
+		}
+		types |= 1 << index
+	}
+	return types, nil
+}
+
+func compileNumber(keyword string, value any, pointer string) (*big.Float, error) {
+	number, isNumber := value.(json.Number)
+	if !isNumber {
+		return nil, fmt.Errorf("%s at %q must be a number", keyword, pointer)
+	}
+	return parseNumber([]byte(number)), nil
+}
+
+func compileCount(keyword string, value any, pointer string) (int, error) {
+	number, isNumber := value.(json.Number)
+	if isNumber {
+		count, err := number.Int64()
+		if err == nil && count >= 0 {
+			return int(count), nil
+		}
+	}
+	return 0, fmt.Errorf("%s at %q must be a non-negative integer", keyword, pointer)
+}
+
+// numberPrecision is the precision in bits with which numbers are compared, which is far more than the 53 bits of a
+// float64, so that numbers which are written differently compare equal if they have the same value. Numbers with more
+// than about 300 significant digits are still rounded, so may compare equal to other numbers that close to them.
+const numberPrecision = 1024
+
+// parseNumber parses raw, the bytes of a valid JSON number. Numbers with exponents too large to be represented are
+// parsed as an infinity, or zero if the exponent is negative. Unlike big.Rat, big.Float does not need to allocate
+// memory in proportion to the exponent, which a hostile document could otherwise make huge.
+func parseNumber(raw []byte) *big.Float {
+	number, isParsed := new(big.Float).SetPrec(numberPrecision).SetString(string(raw))
+	if isParsed {
+		return number
+	}
+	if raw[bytes.IndexAny(raw, "eE")+1] == '-' {
+		return new(big.Float)
+	}
+	return new(big.Float).SetInf(raw[0] == '-')
+}
+
+// isInteger reports whether raw, the bytes of a valid JSON number, has no fractional part. It is found from the digits
+// as written, rather than by parseNumber, so that numbers which are rounded or have exponents too large to be
+// represented are still classified exactly.
+func isInteger(raw []byte) bool {
+	mantissa, exponent := raw, 0
+	exponentIndex := bytes.IndexAny(raw, "eE")
+	if exponentIndex != -1 {
+		mantissa = raw[:exponentIndex]
+		var err error
+		exponent, err = strconv.Atoi(string(raw[exponentIndex+1:]))
+		if err != nil {
+			// The exponent is too large for an int, so its magnitude is far beyond the number of digits of any
+			// mantissa which fits in memory.
+			return raw[exponentIndex+1] != '-' || len(bytes.Trim(mantissa, "-.0")) == 0
+		}
+	}
+	integerDigits := bytes.TrimPrefix(mantissa, []byte("-"))
+	var fractionDigits []byte
+	if pointIndex := bytes.IndexByte(integerDigits, '.'); pointIndex != -1 {
+		integerDigits, fractionDigits = integerDigits[:pointIndex], integerDigits[pointIndex+1:]
+	}
+	// The number is an integer if the last of its digits which is not a zero is still before the point once it has
+	// been moved by the exponent.
+	significantDigits := len(integerDigits) + len(bytes.TrimRight(fractionDigits, "0"))
+	if significantDigits == len(integerDigits) {
+		significantDigits = len(bytes.TrimRight(integerDigits, "0"))
+	}
+	return significantDigits-len(integerDigits) <= exponent
+}
+
+// escapePointer escapes a name to be a segment of a JSON Pointer.
+func escapePointer(name string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(name, "~", "~0"), "/", "~1")
+}
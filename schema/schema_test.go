@@ -0,0 +1,325 @@
+package schema
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/theteacat/jsonbytes"
+)
+
+func TestValidate(t *testing.T) {
+	objectSchema := `{"type": "object", "required": ["a", "b"], ` +
+		`"properties": {"a": {"type": "string"}, "b": {"type": "array", "items": {"type": "integer"}}}}`
+	testCases := []struct {
+		schemaJson         string
+		document           string
+		expectedViolations []Violation
+	}{
+		{`true`, `{"a": [1, "b", null]}`, nil},
+		{`{}`, `{"a": [1, "b", null]}`, nil},
+		{`false`, `1`, []Violation{{"", "false", "no value is allowed"}}},
+		{`{"type": "string"}`, `"a"`, nil},
+		{`{"type": "string"}`, `1`, []Violation{{"", "type", "expected string but found number"}}},
+		{`{"type": ["null", "boolean"]}`, `false`, nil},
+		{`{"type": ["null", "boolean"]}`, `{}`, []Violation{{"", "type", "expected null or boolean but found object"}}},
+		{`{"type": "integer"}`, `1`, nil},
+		{`{"type": "integer"}`, `-1.0e2`, nil},
+		{`{"type": "integer"}`, `1.5`, []Violation{{"", "type", "expected integer but found number"}}},
+		{`{"type": "integer"}`, `150e-2`, []Violation{{"", "type", "expected integer but found number"}}},
+		{`{"type": "integer"}`, `1e999999999`, nil},
+		{`{"type": "integer"}`, `-1.5e999999999`, nil},
+		{`{"type": "integer"}`, `1e1000000000000000000000`, nil},
+		{`{"type": "integer"}`, `0.0e-1000000000000000000000`, nil},
+		{
+			`{"type": "integer"}`, `1.5e-999999999`,
+			[]Violation{{"", "type", "expected integer but found number"}},
+		},
+		{
+			`{"type": "integer"}`, `1e-1000000000000000000000`,
+			[]Violation{{"", "type", "expected integer but found number"}},
+		},
+		{
+			`{"type": "integer"}`, "1." + strings.Repeat("0", 400) + "1",
+			[]Violation{{"", "type", "expected integer but found number"}},
+		},
+		{`{"type": "number"}`, `1.5`, nil},
+		{`{"enum": ["a", 1, null, [1, {"b": true}]]}`, `"a"`, nil},
+		{`{"enum": ["a", 1, null, [1, {"b": true}]]}`, `1.00`, nil},
+		{`{"enum": ["a", 1, null, [1, {"b": true}]]}`, ` [ 10e-1, { "b" : true } ] `, nil},
+		{
+			`{"enum": ["a", 1, null, [1, {"b": true}]]}`, `"b"`,
+			[]Violation{{"", "enum", "expected one of the enumerated values"}},
+		},
+		{
+			`{"enum": ["a", 1, null, [1, {"b": true}]]}`, `[1, {"b": false}]`,
+			[]Violation{{"", "enum", "expected one of the enumerated values"}},
+		},
+		{`{"const": {"a": [1, 2]}}`, `{"a": [1, 2]}`, nil},
+		{`{"const": {"a": [1, 2]}}`, `{"a": [2, 1]}`, []Violation{{"", "const", "expected the constant value"}}},
+		{`{"const": 0}`, `false`, []Violation{{"", "const", "expected the constant value"}}},
+		{`{"minimum": 1, "maximum": 2.5}`, `1`, nil},
+		{`{"minimum": 1, "maximum": 2.5}`, `2.5`, nil},
+		{
+			`{"minimum": 1, "maximum": 2.5}`, `0.999`,
+			[]Violation{{"", "minimum", "expected at least 1 but found 0.999"}},
+		},
+		{`{"minimum": 1, "maximum": 2.5}`, `25e-1`, nil},
+		{
+			`{"minimum": 1, "maximum": 2.5}`, `2.500000000000000000001`,
+			[]Violation{{"", "maximum", "expected at most 2.5 but found 2.500000000000000000001"}},
+		},
+		{`{"minimum": 1, "maximum": 2.5}`, `"0"`, nil},
+		{`{"exclusiveMinimum": 0, "exclusiveMaximum": 1e2}`, `0.1`, nil},
+		{
+			`{"exclusiveMinimum": 0, "exclusiveMaximum": 1e2}`, `-0`,
+			[]Violation{{"", "exclusiveMinimum", "expected more than 0 but found -0"}},
+		},
+		{
+			`{"exclusiveMinimum": 0, "exclusiveMaximum": 1e2}`, `100`,
+			[]Violation{{"", "exclusiveMaximum", "expected less than 100 but found 100"}},
+		},
+		{
+			`{"exclusiveMinimum": 0, "exclusiveMaximum": 1e2}`, `1e99999999999999999999`,
+			[]Violation{{"", "exclusiveMaximum", "expected less than 100 but found 1e99999999999999999999"}},
+		},
+		{
+			`{"exclusiveMinimum": 0, "exclusiveMaximum": 1e2}`, `1e-99999999999999999999`,
+			[]Violation{{"", "exclusiveMinimum", "expected more than 0 but found 1e-99999999999999999999"}},
+		},
+		{`{"minLength": 2, "maxLength": 3}`, `"ab"`, nil},
+		{`{"minLength": 2, "maxLength": 3}`, `"😀é\n"`, nil},
+		{
+			`{"minLength": 2, "maxLength": 3}`, `"😀"`,
+			[]Violation{{"", "minLength", "expected at least 2 characters but found 1"}},
+		},
+		{
+			`{"minLength": 2, "maxLength": 3}`, `"abcd"`,
+			[]Violation{{"", "maxLength", "expected at most 3 characters but found 4"}},
+		},
+		{`{"minLength": 2, "maxLength": 3}`, `1`, nil},
+		{`{"pattern": "^[a-z]+-\\d+$"}`, `"abc-123"`, nil},
+		{`{"pattern": "^[a-z]+-\\d+$"}`, `"abc-1"`, nil},
+		{
+			`{"pattern": "^[a-z]+-\\d+$"}`, `"ABC-123"`,
+			[]Violation{{"", "pattern", `expected a string matching "^[a-z]+-\\d+$"`}},
+		},
+		{
+			objectSchema,
+			`{"a": "x", "b": [1, 2, 3], "c": null}`,
+			nil,
+		},
+		{
+			objectSchema,
+			`{"a": 1, "b": [1, "2", 3.5]}`,
+			[]Violation{
+				{"/a", "type", "expected string but found number"},
+				{"/b/1", "type", "expected integer but found string"},
+				{"/b/2", "type", "expected integer but found number"},
+			},
+		},
+		{
+			objectSchema,
+			`{"c": {"a": 1}}`,
+			[]Violation{
+				{"", "required", `missing required property "a"`},
+				{"", "required", `missing required property "b"`},
+			},
+		},
+		{
+			objectSchema,
+			`["a", "b"]`,
+			[]Violation{{"", "type", "expected object but found array"}},
+		},
+		{
+			`{"properties": {"a/b": {"properties": {"c~d": {"items": {"type": "null"}}}}}}`,
+			`{"a/b": {"c~d": [null, [null]]}}`,
+			[]Violation{{"/a~1b/c~0d/1", "type", "expected null but found array"}},
+		},
+		{
+			`{"properties": {"a\nb": {"type": "null"}}}`,
+			`{"a\u000ab": 1}`,
+			[]Violation{{"/a\nb", "type", "expected null but found number"}},
+		},
+		{
+			`{"properties": {"a": true, "b": false}, "additionalProperties": false}`,
+			`{"a": 1, "b": 2, "c": {"d": 3}, "e": 4}`,
+			[]Violation{
+				{"/b", "false", "no value is allowed"},
+				{"/c", "additionalProperties", `property "c" is not allowed`},
+				{"/e", "additionalProperties", `property "e" is not allowed`},
+			},
+		},
+		{
+			`{"additionalProperties": {"type": "string"}}`,
+			`{"a": "x", "b": 2}`,
+			[]Violation{{"/b", "type", "expected string but found number"}},
+		},
+		{`{"minProperties": 1, "maxProperties": 2}`, `{"a": 1}`, nil},
+		{
+			`{"minProperties": 1, "maxProperties": 2}`, `{}`,
+			[]Violation{{"", "minProperties", "expected at least 1 properties but found 0"}},
+		},
+		{
+			`{"minProperties": 1, "maxProperties": 2}`, `{"a": 1, "b": 2, "c": 3}`,
+			[]Violation{{"", "maxProperties", "expected at most 2 properties but found 3"}},
+		},
+		{`{"minItems": 1, "maxItems": 2}`, `[[]]`, nil},
+		{
+			`{"minItems": 1, "maxItems": 2}`, `[]`,
+			[]Violation{{"", "minItems", "expected at least 1 items but found 0"}},
+		},
+		{
+			`{"minItems": 1, "maxItems": 2}`, `[1, 2, 3]`,
+			[]Violation{{"", "maxItems", "expected at most 2 items but found 3"}},
+		},
+		{`{"items": false}`, `[]`, nil},
+		{
+			`{"items": false}`, `[1, 2]`,
+			[]Violation{{"/0", "false", "no value is allowed"}, {"/1", "false", "no value is allowed"}},
+		},
+		{
+			`{"items": {"type": "object", "required": ["id"], ` +
+				`"properties": {"id": {"type": "integer", "minimum": 1}}}, "maxItems": 1}`,
+			`[{"id": 0}, {"name": "x"}]`,
+			[]Violation{
+				{"/0/id", "minimum", "expected at least 1 but found 0"},
+				{"/1", "required", `missing required property "id"`},
+				{"", "maxItems", "expected at most 1 items but found 2"},
+			},
+		},
+		{`{"unknownKeyword": {"type": "null"}, "$schema": "https://json-schema.org/draft/2020-12/schema"}`, `1`, nil},
+	}
+	for _, testCase := range testCases {
+		t.Run(
+			testCase.schemaJson+" "+testCase.document,
+			func(t *testing.T) {
+				schema, err := Compile([]byte(testCase.schemaJson))
+				require.Nil(t, err)
+				err = schema.Validate([]byte(testCase.document))
+				if testCase.expectedViolations == nil {
+					require.Nil(t, err)
+					return
+				}
+				var validationError *ValidationError
+				require.ErrorAs(t, err, &validationError)
+				require.Equal(t, testCase.expectedViolations, validationError.Violations)
+			},
+		)
+	}
+}
+
+func TestValidateInvalidJsons(t *testing.T) {
+	schema, err := Compile([]byte(`{"type": "object", "properties": {"a": {"type": "string"}}}`))
+	require.Nil(t, err)
+	testCases := []struct {
+		document      string
+		expectedError string
+	}{
+		{``, "jsonvalidator needs more than zero bytes"},
+		{`{"a": 1`, "read head ran out of json"},
+		{`{"a": 1,}`, "expected \" at index 8 but read '}'"},
+		{`{"a": 1} {}`, "failed to consume entire json string"},
+		{`[1, 2]]`, "failed to consume entire json string"},
+	}
+	for _, testCase := range testCases {
+		t.Run(
+			testCase.document,
+			func(t *testing.T) {
+				err := schema.Validate([]byte(testCase.document))
+				require.NotNil(t, err)
+				require.Equal(t, testCase.expectedError, err.Error())
+				var syntaxError *jsonbytes.SyntaxError
+				require.ErrorAs(t, err, &syntaxError)
+			},
+		)
+	}
+	err = schema.ValidateOptions([]byte(`{"a": "x", "a": 1}`), jsonbytes.Options{DisallowDuplicateNames: true})
+	var duplicateNameError *jsonbytes.DuplicateNameError
+	require.ErrorAs(t, err, &duplicateNameError)
+	err = schema.ValidateOptions([]byte(`{"a": [[1]]}`), jsonbytes.Options{MaxDepth: 2})
+	require.NotNil(t, err)
+	require.False(t, errors.As(err, new(*ValidationError)))
+}
+
+func TestValidationError(t *testing.T) {
+	schema, err := Compile([]byte(`{"items": {"type": "string"}, "minItems": 3}`))
+	require.Nil(t, err)
+	err = schema.Validate([]byte(`["a", 1]`))
+	require.NotNil(t, err)
+	require.Equal(
+		t, "\"/1\": expected string but found number\n\"\": expected at least 3 items but found 2", err.Error(),
+	)
+}
+
+func TestCompile(t *testing.T) {
+	testCases := []struct {
+		schemaJson    string
+		expectedError string
+	}{
+		{`{"type": "string"`, "schema is not valid JSON: unexpected EOF"},
+		{`{} {}`, "schema is not valid JSON: more than one value"},
+		{`1`, `schema at "" must be an object or boolean`},
+		{`{"properties": {"a/b": {"items": []}}}`, `schema at "/properties/a~1b/items" must be an object or boolean`},
+		{`{"type": "float"}`, `type at "/type" must be one of null, boolean, object, array, number, integer, string`},
+		{
+			`{"type": ["string", 1]}`,
+			`type at "/type" must be one of null, boolean, object, array, number, integer, string`,
+		},
+		{`{"type": {}}`, `type at "/type" must be a string or an array of strings`},
+		{`{"enum": "a"}`, `enum at "/enum" must be an array`},
+		{`{"minimum": "1"}`, `minimum at "/minimum" must be a number`},
+		{
+			`{"properties": {"a": {"exclusiveMaximum": null}}}`,
+			`exclusiveMaximum at "/properties/a/exclusiveMaximum" must be a number`,
+		},
+		{`{"minLength": 1.5}`, `minLength at "/minLength" must be a non-negative integer`},
+		{`{"maxItems": -1}`, `maxItems at "/maxItems" must be a non-negative integer`},
+		{`{"pattern": 1}`, `pattern at "/pattern" must be a string`},
+		{
+			`{"pattern": "(a"}`,
+			"pattern at \"/pattern\" is not a valid regular expression: error parsing regexp: missing closing ): `(a`",
+		},
+		{`{"properties": []}`, `properties at "/properties" must be an object`},
+		{`{"required": ["a", 1]}`, `required at "/required" must be an array of strings`},
+		{
+			`{"additionalProperties": {"required": "a"}}`,
+			`required at "/additionalProperties/required" must be an array of strings`,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(
+			testCase.schemaJson,
+			func(t *testing.T) {
+				schema, err := Compile([]byte(testCase.schemaJson))
+				require.Nil(t, schema)
+				require.NotNil(t, err)
+				require.Equal(t, testCase.expectedError, err.Error())
+			},
+		)
+	}
+}
+
+func TestCompileInferredSchema(t *testing.T) {
+	samples := [][]byte{
+		[]byte(`{"id": 1, "at": "2024-01-02T03:04:05Z", "tags": ["a"], "owner": {"name": "x"}}`),
+		[]byte(`{"id": 2.5, "at": "2024-01-02T03:04:05Z", "tags": [], "owner": null}`),
+	}
+	inferredSchema, err := jsonbytes.InferSchema(samples...)
+	require.Nil(t, err)
+	schema, err := Compile(inferredSchema)
+	require.Nil(t, err)
+	for _, sample := range samples {
+		require.Nil(t, schema.Validate(sample))
+	}
+	err = schema.Validate([]byte(`{"id": "3", "tags": [1], "owner": {}}`))
+	var validationError *ValidationError
+	require.ErrorAs(t, err, &validationError)
+	require.Equal(t, []Violation{
+		{"/id", "type", "expected number but found string"},
+		{"/tags/0", "type", "expected string but found number"},
+		{"/owner", "required", `missing required property "name"`},
+		{"", "required", `missing required property "at"`},
+	}, validationError.Violations)
+}
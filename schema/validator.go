@@ -0,0 +1,324 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/theteacat/jsonbytes"
+)
+
+// Validate returns nil if document is valid JSON which conforms to schema, else a *ValidationError holding every
+// violation in it. If document is not valid JSON, the error returned by the jsonbytes Tokenizer is returned instead,
+// such as a *jsonbytes.SyntaxError.
+func (schema *Schema) Validate(document []byte) error {
+	return schema.ValidateOptions(document, jsonbytes.Options{})
+}
+
+// ValidateOptions is the same as Validate, but also makes the checks enabled by options.
+func (schema *Schema) ValidateOptions(document []byte, options jsonbytes.Options) error {
+	validator := documentValidator{tokenizer: options.NewTokenizer(document), document: document}
+	err := validator.validateValue(schema.root)
+	if err == nil {
+		_, err = validator.tokenizer.Next()
+		if err == io.EOF {
+			err = nil
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if len(validator.violations) != 0 {
+		return &ValidationError{Violations: validator.violations}
+	}
+	return nil
+}
+
+// documentValidator validates a document against a schema as it reads its tokens.
+type documentValidator struct {
+	tokenizer  *jsonbytes.Tokenizer
+	document   []byte
+	pointer    []byte
+	violations []Violation
+}
+
+func (validator *documentValidator) violate(keyword string, format string, args ...any) {
+	validator.violations = append(validator.violations, Violation{
+		Pointer: string(validator.pointer),
+		Keyword: keyword,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// validateValue reads the tokens of the next value in the document, and checks it against compiled, which may be nil
+// to check nothing.
+func (validator *documentValidator) validateValue(compiled *node) error {
+	token, err := validator.tokenizer.Next()
+	if err != nil {
+		return err
+	}
+	return validator.validateToken(compiled, token)
+}
+
+// validateToken is the same as validateValue, but for the value which begins with token, which has already been read.
+func (validator *documentValidator) validateToken(compiled *node, token jsonbytes.Token) error {
+	var err error
+	valueStart := validator.tokenizer.Offset() - len(token.Raw)
+	if compiled != nil && compiled.never {
+		validator.violate("false", "no value is allowed")
+		compiled = nil
+	}
+	if compiled != nil && !compiled.allowsType(token) {
+		validator.violate("type", "expected %s but found %s", compiled.types, typeOf(token))
+	}
+	switch token.Kind {
+	case jsonbytes.TokenObjectStart:
+		err = validator.validateObject(compiled)
+	case jsonbytes.TokenArrayStart:
+		err = validator.validateArray(compiled)
+	case jsonbytes.TokenString:
+		if compiled != nil {
+			validator.validateString(compiled, token.Raw)
+		}
+	case jsonbytes.TokenNumber:
+		if compiled != nil {
+			validator.validateNumber(compiled, token.Raw)
+		}
+	}
+	if err != nil || compiled == nil {
+		return err
+	}
+	if compiled.hasEnum {
+		validator.validateEnum(compiled, validator.document[valueStart:validator.tokenizer.Offset()])
+	}
+	return nil
+}
+
+func typeOf(token jsonbytes.Token) valueType {
+	switch token.Kind {
+	case jsonbytes.TokenObjectStart:
+		return typeObject
+	case jsonbytes.TokenArrayStart:
+		return typeArray
+	case jsonbytes.TokenString:
+		return typeString
+	case jsonbytes.TokenNumber:
+		return typeNumber
+	case jsonbytes.TokenTrue, jsonbytes.TokenFalse:
+		return typeBoolean
+	}
+	return typeNull
+}
+
+// allowsType reports whether the type of the value which begins with token is allowed by the type keyword. A number
+// is an integer if it has no fractional part, however it is written.
+func (compiled *node) allowsType(token jsonbytes.Token) bool {
+	found := typeOf(token)
+	if compiled.types&found != 0 {
+		return true
+	}
+	return found == typeNumber && compiled.types&typeInteger != 0 && isInteger(token.Raw)
+}
+
+func (validator *documentValidator) validateObject(compiled *node) error {
+	var requiredFound []bool
+	if compiled != nil {
+		requiredFound = make([]bool, len(compiled.required))
+	}
+	pointerLength := len(validator.pointer)
+	properties := 0
+	for {
+		token, err := validator.tokenizer.Next()
+		if err != nil {
+			return err
+		}
+		if token.Kind == jsonbytes.TokenObjectEnd {
+			break
+		}
+		properties += 1
+		name := unquote(token.Raw)
+		validator.pointer = append(validator.pointer, '/')
+		validator.pointer = append(validator.pointer, escapePointer(name)...)
+		var property *node
+		if compiled != nil {
+			for index, requiredName := range compiled.required {
+				if requiredName == name {
+					requiredFound[index] = true
+				}
+			}
+			var isProperty bool
+			property, isProperty = compiled.properties[name]
+			if !isProperty {
+				property = compiled.additional
+				if property != nil && property.never {
+					validator.violate("additionalProperties", "property %q is not allowed", name)
+					property = nil
+				}
+			}
+		}
+		err = validator.validateValue(property)
+		validator.pointer = validator.pointer[:pointerLength]
+		if err != nil {
+			return err
+		}
+	}
+	if compiled == nil {
+		return nil
+	}
+	for index, requiredName := range compiled.required {
+		if !requiredFound[index] {
+			validator.violate("required", "missing required property %q", requiredName)
+		}
+	}
+	if compiled.minProperties != -1 && properties < compiled.minProperties {
+		validator.violate("minProperties", "expected at least %d properties but found %d", compiled.minProperties,
+			properties)
+	}
+	if compiled.maxProperties != -1 && properties > compiled.maxProperties {
+		validator.violate("maxProperties", "expected at most %d properties but found %d", compiled.maxProperties,
+			properties)
+	}
+	return nil
+}
+
+func (validator *documentValidator) validateArray(compiled *node) error {
+	var items *node
+	if compiled != nil {
+		items = compiled.items
+	}
+	pointerLength := len(validator.pointer)
+	count := 0
+	for ; ; count++ {
+		token, err := validator.tokenizer.Next()
+		if err != nil {
+			return err
+		}
+		if token.Kind == jsonbytes.TokenArrayEnd {
+			break
+		}
+		validator.pointer = append(validator.pointer, '/')
+		validator.pointer = strconv.AppendInt(validator.pointer, int64(count), 10)
+		err = validator.validateToken(items, token)
+		validator.pointer = validator.pointer[:pointerLength]
+		if err != nil {
+			return err
+		}
+	}
+	if compiled == nil {
+		return nil
+	}
+	if compiled.minItems != -1 && count < compiled.minItems {
+		validator.violate("minItems", "expected at least %d items but found %d", compiled.minItems, count)
+	}
+	if compiled.maxItems != -1 && count > compiled.maxItems {
+		validator.violate("maxItems", "expected at most %d items but found %d", compiled.maxItems, count)
+	}
+	return nil
+}
+
+func (validator *documentValidator) validateString(compiled *node, raw []byte) {
+	if compiled.minLength == -1 && compiled.maxLength == -1 && compiled.pattern == nil {
+		return
+	}
+	value := unquote(raw)
+	length := utf8.RuneCountInString(value)
+	if compiled.minLength != -1 && length < compiled.minLength {
+		validator.violate("minLength", "expected at least %d characters but found %d", compiled.minLength, length)
+	}
+	if compiled.maxLength != -1 && length > compiled.maxLength {
+		validator.violate("maxLength", "expected at most %d characters but found %d", compiled.maxLength, length)
+	}
+	if compiled.pattern != nil && !compiled.pattern.MatchString(value) {
+		validator.violate("pattern", "expected a string matching %q", compiled.pattern.String())
+	}
+}
+
+func (validator *documentValidator) validateNumber(compiled *node, raw []byte) {
+	if compiled.minimum == nil && compiled.maximum == nil && compiled.exclusiveMinimum == nil &&
+		compiled.exclusiveMaximum == nil {
+		return
+	}
+	number := parseNumber(raw)
+	if compiled.minimum != nil && number.Cmp(compiled.minimum) < 0 {
+		validator.violate("minimum", "expected at least %s but found %s", compiled.minimum.Text('g', -1), raw)
+	}
+	if compiled.maximum != nil && number.Cmp(compiled.maximum) > 0 {
+		validator.violate("maximum", "expected at most %s but found %s", compiled.maximum.Text('g', -1), raw)
+	}
+	if compiled.exclusiveMinimum != nil && number.Cmp(compiled.exclusiveMinimum) <= 0 {
+		validator.violate("exclusiveMinimum", "expected more than %s but found %s",
+			compiled.exclusiveMinimum.Text('g', -1), raw)
+	}
+	if compiled.exclusiveMaximum != nil && number.Cmp(compiled.exclusiveMaximum) >= 0 {
+		validator.violate("exclusiveMaximum", "expected less than %s but found %s",
+			compiled.exclusiveMaximum.Text('g', -1), raw)
+	}
+}
+
+// validateEnum checks that raw, the bytes of a whole value, equals one of the values of the enum or const keyword.
+// It is unmarshalled to be compared, which only happens for values which have such a keyword.
+func (validator *documentValidator) validateEnum(compiled *node, raw []byte) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value any
+	err := decoder.Decode(&value)
+	if err == nil {
+		for _, allowed := range compiled.enum {
+			if equalValues(value, allowed) {
+				return
+			}
+		}
+	}
+	if compiled.enumKeyword == "const" {
+		validator.violate("const", "expected the constant value")
+	} else {
+		validator.violate("enum", "expected one of the enumerated values")
+	}
+}
+
+// equalValues reports whether two values unmarshalled with json.Decoder.UseNumber are equal as JSON Schema defines,
+// where numbers are equal if they have the same mathematical value, regardless of how they are written.
+func equalValues(a any, b any) bool {
+	switch a := a.(type) {
+	case json.Number:
+		b, isNumber := b.(json.Number)
+		return isNumber && parseNumber([]byte(a)).Cmp(parseNumber([]byte(b))) == 0
+	case []any:
+		b, isArray := b.([]any)
+		if !isArray || len(a) != len(b) {
+			return false
+		}
+		for index := range a {
+			if !equalValues(a[index], b[index]) {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		b, isObject := b.(map[string]any)
+		if !isObject || len(a) != len(b) {
+			return false
+		}
+		for name, value := range a {
+			other, found := b[name]
+			if !found || !equalValues(value, other) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+// unquote returns the string encoded by raw, the bytes of a valid JSON string or name including its quotes.
+func unquote(raw []byte) string {
+	if bytes.IndexByte(raw, '\\') == -1 {
+		return string(raw[1 : len(raw)-1])
+	}
+	var value string
+	_ = json.Unmarshal(raw, &value)
+	return value
+}